@@ -0,0 +1,266 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Attrs 是 *Error 上带类型的附加数据, 值只会是下面的类型之一:
+//
+//	string, int64, float64, bool, time.Time, Attrs, []interface{}
+//
+// 在 JSON 中它放在 "attrs" 成员中, "data" 成员仍然只有 Fields, 以便与旧的客户端兼容。
+// time.Time 编码为 RFC 3339 字符串, 解码后仍是字符串, 可以用 AttrTime 转换。
+type Attrs map[string]interface{}
+
+// Set 设置一个值, v 会被转换成 Attrs 支持的类型
+func (attrs Attrs) Set(key string, v interface{}) {
+	attrs[key] = AttrValue(v)
+}
+
+// AttrValue 将 v 转换成 Attrs 支持的类型, 整数转成 int64, 浮点数转成 float64,
+// map 转成 Attrs, slice 和 array 转成 []interface{}, 其它的结构体按它的 JSON 形式转换
+func AttrValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case string, bool, int64, float64, time.Time:
+		return value
+	case Attrs:
+		return value
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case uint32:
+		return int64(value)
+	case float32:
+		return float64(value)
+	case time.Duration:
+		return value.String()
+	case []byte:
+		return string(value)
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		if f, err := value.Float64(); err == nil {
+			return f
+		}
+		return value.String()
+	case json.RawMessage:
+		return decodeAttrValue(value)
+	case error:
+		return value.Error()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return AttrValue(rv.Elem().Interface())
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return float64(u)
+		}
+		return int64(u)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		list := make([]interface{}, rv.Len())
+		for idx := range list {
+			list[idx] = AttrValue(rv.Index(idx).Interface())
+		}
+		return list
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		attrs := make(Attrs, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			attrs[fmt.Sprint(iter.Key().Interface())] = AttrValue(iter.Value().Interface())
+		}
+		return attrs
+	}
+
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return decodeAttrValue(bs)
+}
+
+func decodeAttrValue(bs []byte) interface{} {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return string(bs)
+	}
+	return AttrValue(value)
+}
+
+// AttrTime 将 Attrs 中的时间值转换成 time.Time, 支持 time.Time 和 RFC 3339 字符串
+func AttrTime(v interface{}) (time.Time, bool) {
+	switch value := v.(type) {
+	case time.Time:
+		return value, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// WithValue 设置一个带类型的附加数据
+func (err *Error) WithValue(key string, v interface{}) *Error {
+	if err.Values == nil {
+		err.Values = Attrs{}
+	}
+	err.Values.Set(key, v)
+	return err
+}
+
+// Value 返回一个附加数据, 先找 Values, 再找 Fields
+func (err *Error) Value(key string) (interface{}, bool) {
+	if v, ok := err.Values[key]; ok {
+		return v, true
+	}
+	if list, ok := err.Fields[key]; ok {
+		if len(list) == 1 {
+			return list[0], true
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+type errorJSON struct {
+	Code      int                 `json:"code,omitempty"`
+	Message   string              `json:"message"`
+	Details   string              `json:"details,omitempty"`
+	Data      map[string][]string `json:"data,omitempty"`
+	Attrs     Attrs               `json:"attrs,omitempty"`
+	Internals []Error             `json:"internals,omitempty"`
+	Retry     *RetryHint          `json:"retry,omitempty"`
+}
+
+// setData 将 JSON 中的 "data" 成员放到 Fields 中, 不是字符串数组的值 (其它实现产生的) 放在 Values 中
+func (err *Error) setData(data map[string]json.RawMessage) {
+	for key, raw := range data {
+		var list []string
+		if json.Unmarshal(raw, &list) == nil && list != nil {
+			if err.Fields == nil {
+				err.Fields = map[string][]string{}
+			}
+			err.Fields[key] = list
+			continue
+		}
+
+		if err.Values == nil {
+			err.Values = Attrs{}
+		}
+		err.Values[key] = decodeAttrValue(raw)
+	}
+}
+
+// setAttrs 将 JSON 中的 "attrs" 成员放到 Values 中
+func (err *Error) setAttrs(attrs map[string]json.RawMessage) {
+	for key, raw := range attrs {
+		if err.Values == nil {
+			err.Values = Attrs{}
+		}
+		err.Values[key] = decodeAttrValue(raw)
+	}
+}
+
+// setDataValue 与 setData 相同, 只是值已经解码过了
+func (err *Error) setDataValue(key string, value interface{}) {
+	if list, ok := value.([]interface{}); ok && len(list) > 0 {
+		ss := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				break
+			}
+			ss = append(ss, s)
+		}
+		if len(ss) == len(list) {
+			if err.Fields == nil {
+				err.Fields = map[string][]string{}
+			}
+			err.Fields[key] = ss
+			return
+		}
+	}
+
+	if err.Values == nil {
+		err.Values = Attrs{}
+	}
+	err.Values[key] = AttrValue(value)
+}
+
+func (err Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&errorJSON{
+		Code:      err.Code,
+		Message:   err.Message,
+		Details:   err.Details,
+		Data:      err.Fields,
+		Attrs:     err.Values,
+		Internals: err.Internals,
+		Retry:     err.Retry,
+	})
+}
+
+func (err *Error) UnmarshalJSON(bs []byte) error {
+	var value struct {
+		Code      json.Number                `json:"code,omitempty"`
+		Message   string                     `json:"message"`
+		Details   string                     `json:"details,omitempty"`
+		Data      map[string]json.RawMessage `json:"data,omitempty"`
+		Attrs     map[string]json.RawMessage `json:"attrs,omitempty"`
+		Internals []Error                    `json:"internals,omitempty"`
+		Retry     *RetryHint                 `json:"retry,omitempty"`
+	}
+	if e := json.Unmarshal(bs, &value); e != nil {
+		return e
+	}
+
+	*err = Error{
+		Message:   value.Message,
+		Details:   value.Details,
+		Internals: value.Internals,
+		Retry:     value.Retry,
+	}
+	if value.Code != "" {
+		code, e := strconv.Atoi(value.Code.String())
+		if e != nil {
+			return Wrap(e, "unmarshal error code")
+		}
+		err.Code = code
+	}
+	err.setData(value.Data)
+	err.setAttrs(value.Attrs)
+	return nil
+}
@@ -0,0 +1,100 @@
+package errors
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithFieldKeepsDataCompatible(t *testing.T) {
+	b := Build(400000, "bad").WithField("age", 3).WithField("name", "tom")
+
+	if got := b.Fields()["age"]; !reflect.DeepEqual(got, []string{"3"}) {
+		t.Errorf("Fields()[age]: got %#v", got)
+	}
+	if got := b.FieldsWithDefault()["name"]; !reflect.DeepEqual(got, []string{"tom"}) {
+		t.Errorf("FieldsWithDefault()[name]: got %#v", got)
+	}
+
+	bs, err := json.Marshal(b.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧的客户端按 map[string][]string 解码 "data"
+	var old struct {
+		Data map[string][]string `json:"data"`
+	}
+	if err := json.Unmarshal(bs, &old); err != nil {
+		t.Fatalf("old client: %v, json: %s", err, bs)
+	}
+	if !reflect.DeepEqual(old.Data["age"], []string{"3"}) {
+		t.Errorf("data.age: got %#v", old.Data["age"])
+	}
+
+	var e Error
+	if err := json.Unmarshal(bs, &e); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := e.Value("age"); v != int64(3) {
+		t.Errorf("Value(age): got %#v (%T), json: %s", v, v, bs)
+	}
+	if v, _ := e.Value("name"); v != "tom" {
+		t.Errorf("Value(name): got %#v", v)
+	}
+}
+
+func TestAttrsRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := NewError(ErrBadArgument.Code, "bad").
+		WithValue("count", 2).
+		WithValue("ratio", 0.5).
+		WithValue("ok", true).
+		WithValue("at", at)
+
+	bs, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e Error
+	if err := json.Unmarshal(bs, &e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Fields) != 0 {
+		t.Errorf("Fields: got %#v", e.Fields)
+	}
+	want := Attrs{"count": int64(2), "ratio": 0.5, "ok": true, "at": at.Format(time.RFC3339)}
+	if !reflect.DeepEqual(e.Values, want) {
+		t.Errorf("Values: got %#v, want %#v", e.Values, want)
+	}
+	if got, ok := AttrTime(e.Values["at"]); !ok || !got.Equal(at) {
+		t.Errorf("AttrTime: got %v %v", got, ok)
+	}
+}
+
+func TestAttrValue(t *testing.T) {
+	type point struct {
+		X int `json:"x"`
+	}
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{3, int64(3)},
+		{uint8(4), int64(4)},
+		{float32(1.5), 1.5},
+		{[]byte("ab"), "ab"},
+		{time.Second, "1s"},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{map[string]int{"a": 1}, Attrs{"a": int64(1)}},
+		{point{X: 1}, Attrs{"x": int64(1)}},
+		{(*point)(nil), nil},
+	}
+	for _, test := range tests {
+		if got := AttrValue(test.in); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("AttrValue(%#v): got %#v, want %#v", test.in, got, test.want)
+		}
+	}
+}
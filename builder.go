@@ -8,12 +8,13 @@ type ErrorBuilder struct {
 	code      int
 	message   string
 	fields    map[string][]string
+	values    Attrs
 	internals []Error
 }
 
 func (err *ErrorBuilder) WithInternalError(e error) *ErrorBuilder {
 	if rerr, ok := e.(*Error); ok {
-		if rerr.HTTPCode() == ToHttpStatus(ErrMultipleError.ErrorCode()) {
+		if rerr.isMultiple() {
 			err.internals = append(err.internals, rerr.Internals...)
 			return err
 		}
@@ -33,14 +34,30 @@ func (err *ErrorBuilder) WithInternalErrors(internals []*Error) *ErrorBuilder {
 	return err
 }
 
+// WithField 添加一个附加数据, 值总是转换成字符串追加到 Fields 中, 不是字符串的值还会保留类型放在 Values 中
 func (err *ErrorBuilder) WithField(nm string, v interface{}) *ErrorBuilder {
 	if nil == err.fields {
 		err.fields = map[string][]string{}
 	}
-	err.fields[nm] = append(err.fields[nm], fmt.Sprint(v))
+	switch value := v.(type) {
+	case string:
+		err.fields[nm] = append(err.fields[nm], value)
+	case []string:
+		err.fields[nm] = append(err.fields[nm], value...)
+	default:
+		err.fields[nm] = append(err.fields[nm], fmt.Sprint(v))
+		if nil == err.values {
+			err.values = Attrs{}
+		}
+		err.values.Set(nm, v)
+	}
 	return err
 }
 
+func (err *ErrorBuilder) Values() Attrs {
+	return err.values
+}
+
 func (err *ErrorBuilder) Fields() map[string][]string {
 	return err.fields
 }
@@ -54,10 +71,14 @@ func (err *ErrorBuilder) FieldsWithDefault() map[string][]string {
 
 func (err *ErrorBuilder) Build() *Error {
 	var fields map[string][]string
+	var values Attrs
 	var internals []Error
 	if len(err.fields) > 0 {
 		fields = err.fields
 	}
+	if len(err.values) > 0 {
+		values = err.values
+	}
 
 	if len(err.internals) > 0 {
 		internals = err.internals
@@ -67,7 +88,9 @@ func (err *ErrorBuilder) Build() *Error {
 		Code:      err.code,
 		Message:   err.message,
 		Fields:    fields,
+		Values:    values,
 		Internals: internals,
+		stack:     captureStack(err.code, 1),
 	}
 }
 
@@ -80,6 +103,7 @@ func Build(code int, msg string) *ErrorBuilder {
 
 func ReBuildFromRuntimeError(e RuntimeError) *ErrorBuilder {
 	var fields map[string][]string
+	var values Attrs
 	var internals []Error
 	if err, ok := e.(*Error); ok {
 		if len(err.Fields) > 0 {
@@ -88,6 +112,12 @@ func ReBuildFromRuntimeError(e RuntimeError) *ErrorBuilder {
 				fields[k] = v
 			}
 		}
+		if len(err.Values) > 0 {
+			values = Attrs{}
+			for k, v := range err.Values {
+				values[k] = v
+			}
+		}
 
 		if len(err.Internals) > 0 {
 			internals = make([]Error, len(err.Internals))
@@ -98,6 +128,7 @@ func ReBuildFromRuntimeError(e RuntimeError) *ErrorBuilder {
 		code:      e.ErrorCode(),
 		message:   e.Error(),
 		fields:    fields,
+		values:    values,
 		internals: internals,
 	}
 }
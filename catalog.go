@@ -0,0 +1,434 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale 找不到对应语言的消息时最后使用的语言
+var DefaultLocale = "en"
+
+// Catalog 是一种语言下错误码到消息模板的映射
+//
+// 消息模板中可以用 {name} 引用命名参数, 如 "'{name}' is required"。
+type Catalog map[int]string
+
+// Templates 是一种语言下按名称索引的消息模板, 用于 Required, BadArgument 和 NotFound 等
+// 带参数的错误, 见 (*Error).WithTemplate
+type Templates map[string]string
+
+var catalogs = struct {
+	sync.RWMutex
+	byLocale  map[string]Catalog
+	templates map[string]Templates
+}{
+	byLocale:  map[string]Catalog{},
+	templates: map[string]Templates{},
+}
+
+// RegisterCatalog 为 locale 注册消息, 已存在的错误码会被覆盖
+func RegisterCatalog(locale string, catalog Catalog) {
+	locale = normalizeLocale(locale)
+
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	c := catalogs.byLocale[locale]
+	if c == nil {
+		c = Catalog{}
+		catalogs.byLocale[locale] = c
+	}
+	for code, msg := range catalog {
+		c[code] = msg
+	}
+}
+
+// RegisterTemplates 为 locale 注册按名称索引的消息模板, 已存在的名称会被覆盖
+func RegisterTemplates(locale string, templates Templates) {
+	locale = normalizeLocale(locale)
+
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	t := catalogs.templates[locale]
+	if t == nil {
+		t = Templates{}
+		catalogs.templates[locale] = t
+	}
+	for key, msg := range templates {
+		t[key] = msg
+	}
+}
+
+func lookupCatalog(locale string, code int) (string, bool) {
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+	msg, ok := catalogs.byLocale[locale][code]
+	return msg, ok
+}
+
+func lookupTemplates(locale string, key string) (string, bool) {
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+	msg, ok := catalogs.templates[locale][key]
+	return msg, ok
+}
+
+// messageTemplate 是 *Error 上的命名模板和它的参数
+type messageTemplate struct {
+	key    string
+	params map[string]interface{}
+	// message 设置模板时的 Message, Message 被修改 (如 Wrap) 后不再使用模板
+	message string
+}
+
+// WithTemplate 为错误指定一个命名的消息模板和它的参数, LocalizedMessage 会用 locale 下的这个模板
+// 生成消息。它应该在 Message 确定之后调用, 之后 Message 被修改时模板不再使用。
+func (err *Error) WithTemplate(key string, params map[string]interface{}) *Error {
+	err.template = &messageTemplate{key: key, params: params, message: err.Message}
+	return err
+}
+
+// LocalizedTemplate 返回名为 key 的模板在 locale 下展开后的消息, 找不到模板或缺少参数时返回 false
+func LocalizedTemplate(key, locale string, params map[string]interface{}) (string, bool) {
+	tmpl, ok := findMessage(locale, func(l string) (string, bool) {
+		return lookupTemplates(l, key)
+	})
+	if !ok {
+		return "", false
+	}
+	return expandMessage(tmpl, nil, []map[string]interface{}{params})
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// LocaleFallbacks 返回 locale 的回退链, 如 "zh-Hans-CN" 返回 ["zh-hans-cn", "zh-hans", "zh", "en"]
+func LocaleFallbacks(locale string) []string {
+	list := localeChain(locale)
+	def := normalizeLocale(DefaultLocale)
+	if def == "" {
+		return list
+	}
+	for _, l := range list {
+		if l == def {
+			return list
+		}
+	}
+	return append(list, def)
+}
+
+func localeChain(locale string) []string {
+	var list []string
+	locale = normalizeLocale(locale)
+	for locale != "" {
+		list = append(list, locale)
+		pos := strings.LastIndexByte(locale, '-')
+		if pos < 0 {
+			break
+		}
+		locale = locale[:pos]
+	}
+	return list
+}
+
+// ParseAcceptLanguage 解析 Accept-Language 头, 按权重从高到低返回语言
+func ParseAcceptLanguage(header string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, q := part, 1.0
+		if pos := strings.IndexByte(part, ';'); pos >= 0 {
+			tag = strings.TrimSpace(part[:pos])
+			param := strings.TrimSpace(part[pos+1:])
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		langs = append(langs, lang{tag: tag, q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	list := make([]string, len(langs))
+	for idx := range langs {
+		list[idx] = langs[idx].tag
+	}
+	return list
+}
+
+// LocalizedMessage 返回 err 在 locale 下的消息, locale 可以是一个语言标签也可以是 Accept-Language 头的值
+//
+// err 带有命名模板 (见 WithTemplate) 时按 locale 的回退链查找这个模板; 否则只有 err 的消息是它的
+// 错误码注册时的缺省消息时, 才查找错误码对应的消息模板, 具体的消息 (如 Wrap 添加的上下文) 不会被替换。
+// 模板中的 {name} 依次用 params, 模板的参数和 err 的 Fields 中的值替换。
+// 找不到模板或缺少参数时返回 err.Error(), err 中保存的消息不会被修改。
+func LocalizedMessage(err error, locale string, params ...map[string]interface{}) string {
+	if err == nil {
+		return ""
+	}
+	code, ok := GetErrorCode(err)
+	if !ok {
+		return err.Error()
+	}
+
+	if e, ok := err.(*Error); ok && e.isMultiple() {
+		title := e.Message
+		if title == "" || title == ErrMultipleError.Message {
+			title = localizedTemplate(code, locale, e.Message)
+		}
+		var sb strings.Builder
+		sb.WriteString(strings.TrimSuffix(title, ":"))
+		sb.WriteString(":")
+		for idx := range e.Internals {
+			sb.WriteString("\r\n  ")
+			sb.WriteString(LocalizedMessage(&e.Internals[idx], locale, params...))
+		}
+		return sb.String()
+	}
+
+	if e, ok := err.(*Error); ok && e.template != nil && e.template.message == e.Message {
+		tmpl, ok := findMessage(locale, func(l string) (string, bool) {
+			return lookupTemplates(l, e.template.key)
+		})
+		if ok {
+			if msg, ok := expandMessage(tmpl, err, append(params[:len(params):len(params)], e.template.params)); ok {
+				return msg
+			}
+		}
+	}
+
+	if !isDefaultMessage(err, code) {
+		return err.Error()
+	}
+	if tmpl, ok := findTemplate(code, locale); ok {
+		if msg, ok := expandMessage(tmpl, err, params); ok {
+			return msg
+		}
+	}
+	return err.Error()
+}
+
+// isDefaultMessage 判断 err 的消息是否为空或是错误码注册时的缺省消息
+func isDefaultMessage(err error, code int) bool {
+	msg := err.Error()
+	if e, ok := err.(*Error); ok {
+		msg = e.Message
+	}
+	if msg == "" {
+		return true
+	}
+	info, ok := LookupCode(code)
+	return ok && msg == info.Message
+}
+
+func localizedTemplate(code int, locale, defaultValue string) string {
+	if tmpl, ok := findTemplate(code, locale); ok {
+		return tmpl
+	}
+	return defaultValue
+}
+
+func findTemplate(code int, locale string) (string, bool) {
+	return findMessage(locale, func(l string) (string, bool) {
+		return lookupCatalog(l, code)
+	})
+}
+
+// findMessage 按 locale 的回退链和 DefaultLocale 依次调用 lookup, 返回第一个找到的模板
+func findMessage(locale string, lookup func(locale string) (string, bool)) (string, bool) {
+	for _, tag := range ParseAcceptLanguage(locale) {
+		for _, l := range localeChain(tag) {
+			if tmpl, ok := lookup(l); ok {
+				return tmpl, true
+			}
+		}
+	}
+	for _, l := range LocaleFallbacks(DefaultLocale) {
+		if tmpl, ok := lookup(l); ok {
+			return tmpl, true
+		}
+	}
+	return "", false
+}
+
+// LocalizedMessageForRequest 按请求的 Accept-Language 头返回 err 的消息
+func LocalizedMessageForRequest(err error, r *http.Request, params ...map[string]interface{}) string {
+	var locale string
+	if r != nil {
+		locale = r.Header.Get("Accept-Language")
+	}
+	return LocalizedMessage(err, locale, params...)
+}
+
+// expandMessage 替换模板中的 {name}, 有参数找不到时返回 false
+func expandMessage(tmpl string, err error, params []map[string]interface{}) (string, bool) {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl, true
+	}
+
+	var fields map[string][]string
+	var e *Error
+	if err != nil && As(err, &e) {
+		fields = e.Fields
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		name := tmpl[start+1 : end]
+		sb.WriteString(tmpl[:start])
+		value, ok := lookupParam(name, params, fields)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(value)
+		tmpl = tmpl[end+1:]
+	}
+	sb.WriteString(tmpl)
+	return sb.String(), true
+}
+
+func lookupParam(name string, params []map[string]interface{}, fields map[string][]string) (string, bool) {
+	for _, m := range params {
+		if value, ok := m[name]; ok {
+			return fmt.Sprint(value), true
+		}
+	}
+	if values := fields[name]; len(values) > 0 {
+		return strings.Join(values, ", "), true
+	}
+	return "", false
+}
+
+func init() {
+	RegisterCatalog("en", Catalog{
+		ErrTimeout.Code:               "timeout",
+		ErrNotFound.Code:              "not found",
+		ErrFieldNotExists.Code:        "field is not found",
+		ErrKeyNotFound.Code:           "key does not exist",
+		ErrRecordNotFound.Code:        "record is not found",
+		ErrValueNotFound.Code:         "value is not found",
+		ErrDisabled.Code:              "disabled",
+		ErrNotAcceptable.Code:         "not acceptable",
+		ErrNotImplemented.Code:        "not implemented",
+		ErrPending.Code:               "pending",
+		ErrRequired.Code:              "'{name}' is required",
+		ErrPermission.Code:            "permission denied",
+		ErrUnauthorized.Code:          "user is unauthorized",
+		ErrTypeError.Code:             "type error",
+		ErrValueNull.Code:             "value is null",
+		ErrNetworkError.Code:          "network error",
+		ErrConnectionRefused.Code:     "connection refused",
+		ErrConnectionReset.Code:       "connection reset",
+		ErrDNSError.Code:              "dns lookup failed",
+		ErrTLSError.Code:              "tls handshake failed",
+		ErrHostUnreachable.Code:       "host is unreachable",
+		ErrInterruptError.Code:        "interrupt error",
+		ErrMultipleError.Code:         "multiple errors occurred",
+		ErrTableNotExists.Code:        "table does not exist",
+		ErrResultEmpty.Code:           "results are empty",
+		ErrMultipleValues.Code:        "multiple values meet the conditions",
+		ErrBodyEmpty.Code:             "body is empty",
+		ErrAlreadyClosed.Code:         "already closed",
+		ErrTxDone.Code:                "transaction has already been committed or rolled back",
+		ErrAlreadyStart.Code:          "already started",
+		ErrReadResponseFail.Code:      "read response error",
+		ErrUnmarshalResponseFail.Code: "unmarshal response error",
+		ErrBadArgument.Code:           "bad argument",
+		ErrArgumentEmpty.Code:         "empty",
+		ErrValidationError.Code:       "validation error",
+		ErrNoContent.Code:             "no content",
+		ErrConflict.Code:              "conflict",
+	})
+	zh := Catalog{
+		ErrTimeout.Code:               "超时",
+		ErrNotFound.Code:              "未找到",
+		ErrFieldNotExists.Code:        "字段不存在",
+		ErrKeyNotFound.Code:           "键不存在",
+		ErrRecordNotFound.Code:        "记录不存在",
+		ErrValueNotFound.Code:         "值不存在",
+		ErrDisabled.Code:              "已禁用",
+		ErrNotAcceptable.Code:         "不可接受",
+		ErrNotImplemented.Code:        "未实现",
+		ErrPending.Code:               "处理中",
+		ErrRequired.Code:              "'{name}' 不能为空",
+		ErrPermission.Code:            "没有权限",
+		ErrUnauthorized.Code:          "用户未认证",
+		ErrTypeError.Code:             "类型错误",
+		ErrValueNull.Code:             "值为空",
+		ErrNetworkError.Code:          "网络错误",
+		ErrConnectionRefused.Code:     "连接被拒绝",
+		ErrConnectionReset.Code:       "连接被重置",
+		ErrDNSError.Code:              "域名解析失败",
+		ErrTLSError.Code:              "TLS 握手失败",
+		ErrHostUnreachable.Code:       "主机不可达",
+		ErrInterruptError.Code:        "已中断",
+		ErrMultipleError.Code:         "发生多个错误",
+		ErrTableNotExists.Code:        "表不存在",
+		ErrResultEmpty.Code:           "结果为空",
+		ErrMultipleValues.Code:        "有多个值满足条件",
+		ErrBodyEmpty.Code:             "请求体为空",
+		ErrAlreadyClosed.Code:         "已关闭",
+		ErrTxDone.Code:                "事务已提交或已回滚",
+		ErrAlreadyStart.Code:          "已启动",
+		ErrReadResponseFail.Code:      "读响应失败",
+		ErrUnmarshalResponseFail.Code: "解析响应失败",
+		ErrBadArgument.Code:           "参数错误",
+		ErrArgumentEmpty.Code:         "参数为空",
+		ErrValidationError.Code:       "校验失败",
+		ErrNoContent.Code:             "没有内容",
+		ErrConflict.Code:              "冲突",
+	}
+	RegisterCatalog("zh-CN", zh)
+	RegisterCatalog("zh", zh)
+
+	// 英文的模板与 Required, BadArgument 和 NotFound 等生成的消息相同
+	RegisterTemplates("en", Templates{
+		"required":           "'{name}' is required.",
+		"argument_missing":   "param '{name}' is missing",
+		"bad_argument":       "param '{name}' is invalid",
+		"bad_argument_error": "param '{name}' is invalid - {error}",
+		"not_found_id":       "record with id is '{id}' isn't found",
+		"not_found_type_id":  "record with type is '{type}' and id is '{id}' isn't found",
+		"field_not_exists":   "field '{field}' is not exists",
+		"record_not_found":   "'{id}' is not found.",
+	})
+	zhTemplates := Templates{
+		"required":           "'{name}' 不能为空",
+		"argument_missing":   "缺少参数 '{name}'",
+		"bad_argument":       "参数 '{name}' 无效",
+		"bad_argument_error": "参数 '{name}' 无效 - {error}",
+		"not_found_id":       "ID 为 '{id}' 的记录不存在",
+		"not_found_type_id":  "类型为 '{type}' 且 ID 为 '{id}' 的记录不存在",
+		"field_not_exists":   "字段 '{field}' 不存在",
+		"record_not_found":   "'{id}' 不存在",
+	}
+	RegisterTemplates("zh-CN", zhTemplates)
+	RegisterTemplates("zh", zhTemplates)
+}
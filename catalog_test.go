@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"io"
+	"testing"
+)
+
+func TestLocalizedMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		locale string
+		params []map[string]interface{}
+		want   string
+	}{
+		{"default message", ErrTimeout, "zh-CN", nil, "超时"},
+		{"accept-language", ErrTimeout, "fr;q=0.5, zh-Hans-CN", nil, "超时"},
+		{"fallback", ErrTimeout, "fr", nil, "timeout"},
+		{"copied", &Error{Code: ErrConflict.Code, Message: ErrConflict.Message}, "zh", nil, "冲突"},
+		{"specific message", NewError(ErrTimeout.Code, "query users timed out"), "zh-CN", nil, "query users timed out"},
+		{"wrapped", Wrap(ErrTimeout, "query users"), "en", nil, "query users: timeout"},
+		{"wrapped zh", Wrap(ErrTimeout, "query users"), "zh-CN", nil, "query users: timeout"},
+		{"not found id", NotFound(42), "zh-CN", nil, "ID 为 '42' 的记录不存在"},
+		{"not found type", NotFound(42, "user"), "zh-CN", nil, "类型为 'user' 且 ID 为 '42' 的记录不存在"},
+		{"not found en", NotFound(42), "en", nil, "record with id is '42' isn't found"},
+		{"bad argument fr", BadArgument("age", 3), "fr", nil, "param 'age' is invalid"},
+		{"bad argument zh", BadArgument("age", 3), "zh", nil, "参数 'age' 无效"},
+		{"bad argument error", BadArgument("age", 3, io.EOF), "zh", nil, "参数 'age' 无效 - EOF"},
+		{"required", Required("name"), "zh-CN", nil, "'name' 不能为空"},
+		{"wrapped required", Wrap(Required("name"), "create"), "zh-CN", nil, "create: 'name' is required."},
+		{"ErrRequired", ErrRequired, "en", []map[string]interface{}{{"name": "age"}}, "'age' is required"},
+		{"ErrRequired zh", ErrRequired, "zh", []map[string]interface{}{{"name": "age"}}, "'age' 不能为空"},
+		{"ErrRequired without name", ErrRequired, "zh", nil, "required"},
+		{"ErrIDNotExists", ErrIDNotExists, "zh", nil, "'id' 不能为空"},
+		{"unknown code", io.EOF, "zh", nil, "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := LocalizedMessage(test.err, test.locale, test.params...); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLocalizedMessageKeepsStoredMessage(t *testing.T) {
+	err := NotFound(42)
+	before := err.Error()
+	LocalizedMessage(err, "zh-CN")
+	if err.Error() != before {
+		t.Errorf("message changed: %q", err.Error())
+	}
+}
+
+func TestLocalizedMessageMultiple(t *testing.T) {
+	err := Combine("", ErrTimeout, NotFound(1))
+	want := "发生多个错误:\r\n  超时\r\n  ID 为 '1' 的记录不存在"
+	if got := LocalizedMessage(err, "zh-CN"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithTemplate(t *testing.T) {
+	RegisterTemplates("en", Templates{"test_quota": "quota of {user} is exceeded"})
+	RegisterTemplates("zh", Templates{"test_quota": "{user} 的配额已用完"})
+
+	err := NewError(ErrPermission.Code, "quota of tom is exceeded").
+		WithTemplate("test_quota", map[string]interface{}{"user": "tom"})
+	if got := LocalizedMessage(err, "zh-CN"); got != "tom 的配额已用完" {
+		t.Errorf("got %q", got)
+	}
+
+	err.Message = "changed"
+	if got := LocalizedMessage(err, "zh-CN"); got != "changed" {
+		t.Errorf("a changed message should not use the template, got %q", got)
+	}
+
+	if got, ok := LocalizedTemplate("test_quota", "zh", map[string]interface{}{"user": "bob"}); !ok || got != "bob 的配额已用完" {
+		t.Errorf("LocalizedTemplate: got %q %v", got, ok)
+	}
+	if _, ok := LocalizedTemplate("test_quota", "zh", nil); ok {
+		t.Error("LocalizedTemplate should fail without params")
+	}
+}
@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"sort"
+	"sync"
+)
+
+// Classifier 为不带错误码的错误 (第三方库、驱动等) 确定错误码
+//
+// 它会在 GetErrorCode 和 GetHttpCode 中被调用, 所以它自己不能调用这两个函数以及
+// ToError, HTTPCode 等依赖它们的函数。
+type Classifier func(err error) (code int, ok bool)
+
+type classifierEntry struct {
+	name     string
+	priority int
+	seq      int
+	fn       Classifier
+}
+
+var classifiers = struct {
+	sync.RWMutex
+	seq  int
+	list []classifierEntry
+}{}
+
+// RegisterClassifier 注册一个 Classifier, priority 大的先执行, 相同时先注册的先执行。
+//
+// name 相同的 Classifier 只保留最后注册的一个, 所以可以用同样的 name 覆盖已有的分类,
+// fn 为 nil 时删除它。
+func RegisterClassifier(name string, priority int, fn Classifier) {
+	classifiers.Lock()
+	defer classifiers.Unlock()
+
+	// 总是创建新的切片, 这样 Classify 可以在不持有锁的情况下遍历旧的切片
+	list := make([]classifierEntry, 0, len(classifiers.list)+1)
+	for _, entry := range classifiers.list {
+		if entry.name != name {
+			list = append(list, entry)
+		}
+	}
+	if fn != nil {
+		classifiers.seq++
+		list = append(list, classifierEntry{
+			name:     name,
+			priority: priority,
+			seq:      classifiers.seq,
+			fn:       fn,
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].priority != list[j].priority {
+			return list[i].priority > list[j].priority
+		}
+		return list[i].seq < list[j].seq
+	})
+	classifiers.list = list
+}
+
+// UnregisterClassifier 删除一个 Classifier
+func UnregisterClassifier(name string) {
+	RegisterClassifier(name, 0, nil)
+}
+
+// Classify 依次用已注册的 Classifier 为 err 确定错误码
+func Classify(err error) (int, bool) {
+	if err == nil {
+		return 0, false
+	}
+
+	classifiers.RLock()
+	list := classifiers.list
+	classifiers.RUnlock()
+
+	for _, entry := range list {
+		if code, ok := entry.fn(err); ok {
+			return code, true
+		}
+	}
+	return 0, false
+}
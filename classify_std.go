@@ -0,0 +1,58 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+)
+
+// BuiltinClassifierPriority 内置的标准库错误分类使用的优先级, 比默认的 0 低,
+// 所以用默认优先级注册的 Classifier 会先执行
+const BuiltinClassifierPriority = -100
+
+// builtinClassifiers 标准库中的错误到错误码的映射, 用它的 name 调用 RegisterClassifier
+// 或 UnregisterClassifier 可以覆盖或删除其中的映射
+var builtinClassifiers = []struct {
+	name   string
+	target error
+	code   *Error
+}{
+	{name: "sql.ErrNoRows", target: sql.ErrNoRows, code: ErrNotFound},
+	{name: "sql.ErrTxDone", target: sql.ErrTxDone, code: ErrTxDone},
+	{name: "sql.ErrConnDone", target: sql.ErrConnDone, code: ErrAlreadyClosed},
+	{name: "fs.ErrNotExist", target: fs.ErrNotExist, code: ErrNotFound},
+	{name: "fs.ErrPermission", target: fs.ErrPermission, code: ErrPermission},
+	{name: "fs.ErrExist", target: fs.ErrExist, code: ErrConflict},
+	{name: "context.DeadlineExceeded", target: context.DeadlineExceeded, code: ErrTimeout},
+	{name: "os.ErrDeadlineExceeded", target: os.ErrDeadlineExceeded, code: ErrTimeout},
+	{name: "context.Canceled", target: context.Canceled, code: ErrInterruptError},
+	{name: "io.ErrUnexpectedEOF", target: io.ErrUnexpectedEOF, code: ErrNetworkError},
+	{name: "net.ErrClosed", target: net.ErrClosed, code: ErrAlreadyClosed},
+}
+
+func init() {
+	// 实现了 Timeout() bool 的错误, 如 net.Error 和 *url.Error
+	RegisterClassifier("Timeout()", BuiltinClassifierPriority, func(err error) (int, bool) {
+		found := Walk(err, func(e error) bool {
+			t, ok := e.(interface{ Timeout() bool })
+			return ok && t.Timeout()
+		})
+		if found {
+			return ErrTimeout.Code, true
+		}
+		return 0, false
+	})
+
+	for _, c := range builtinClassifiers {
+		target, code := c.target, c.code.Code
+		RegisterClassifier(c.name, BuiltinClassifierPriority, func(err error) (int, bool) {
+			if Is(err, target) {
+				return code, true
+			}
+			return 0, false
+		})
+	}
+}
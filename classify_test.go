@@ -0,0 +1,168 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// saveClassifiers 在测试结束时恢复已注册的 Classifier
+func saveClassifiers(t *testing.T) {
+	classifiers.RLock()
+	list, seq := classifiers.list, classifiers.seq
+	classifiers.RUnlock()
+	t.Cleanup(func() {
+		classifiers.Lock()
+		classifiers.list, classifiers.seq = list, seq
+		classifiers.Unlock()
+	})
+}
+
+func classifyAs(code int, target error) Classifier {
+	return func(err error) (int, bool) {
+		if Is(err, target) {
+			return code, true
+		}
+		return 0, false
+	}
+}
+
+func TestClassifierPriority(t *testing.T) {
+	saveClassifiers(t)
+	target := New("test classifier")
+
+	RegisterClassifier("test.low", 5, classifyAs(400901, target))
+	RegisterClassifier("test.first", 10, classifyAs(400902, target))
+	RegisterClassifier("test.second", 10, classifyAs(400903, target))
+	if code, ok := Classify(fmt.Errorf("x: %w", target)); !ok || code != 400902 {
+		t.Errorf("the highest priority registered first should win, got %d %v", code, ok)
+	}
+
+	RegisterClassifier("test.high", 20, classifyAs(400904, target))
+	if code, _ := Classify(target); code != 400904 {
+		t.Errorf("a higher priority should win, got %d", code)
+	}
+	if code, ok := GetErrorCode(target); !ok || code != 400904 {
+		t.Errorf("GetErrorCode: got %d %v", code, ok)
+	}
+	if code, ok := GetHttpCode(target); !ok || code != 400 {
+		t.Errorf("GetHttpCode: got %d %v", code, ok)
+	}
+
+	if _, ok := Classify(New("other")); ok {
+		t.Error("an unknown error should not be classified")
+	}
+	if _, ok := Classify(nil); ok {
+		t.Error("nil should not be classified")
+	}
+}
+
+func TestClassifierOverride(t *testing.T) {
+	saveClassifiers(t)
+	target := New("test classifier")
+
+	RegisterClassifier("test.a", 10, classifyAs(400901, target))
+	RegisterClassifier("test.b", 5, classifyAs(400902, target))
+
+	// 同名的 Classifier 被替换, 新的优先级生效
+	RegisterClassifier("test.a", 1, classifyAs(400903, target))
+	if code, _ := Classify(target); code != 400902 {
+		t.Errorf("override: got %d", code)
+	}
+	classifiers.RLock()
+	var count int
+	for _, entry := range classifiers.list {
+		if entry.name == "test.a" {
+			count++
+		}
+	}
+	classifiers.RUnlock()
+	if count != 1 {
+		t.Errorf("a name should be registered once, got %d", count)
+	}
+
+	UnregisterClassifier("test.b")
+	if code, _ := Classify(target); code != 400903 {
+		t.Errorf("unregister: got %d", code)
+	}
+	RegisterClassifier("test.a", 0, nil)
+	if _, ok := Classify(target); ok {
+		t.Error("a nil fn should remove the classifier")
+	}
+}
+
+func TestClassifierOverrideBuiltin(t *testing.T) {
+	saveClassifiers(t)
+
+	// 默认优先级的 Classifier 在内置的之前执行
+	RegisterClassifier("test.eof", 0, classifyAs(ErrConflict.Code, io.ErrUnexpectedEOF))
+	if code, _ := Classify(io.ErrUnexpectedEOF); code != ErrConflict.Code {
+		t.Errorf("got %d", code)
+	}
+	UnregisterClassifier("test.eof")
+
+	RegisterClassifier("io.ErrUnexpectedEOF", BuiltinClassifierPriority, classifyAs(ErrConflict.Code, io.ErrUnexpectedEOF))
+	if code, _ := Classify(io.ErrUnexpectedEOF); code != ErrConflict.Code {
+		t.Errorf("override builtin: got %d", code)
+	}
+	UnregisterClassifier("io.ErrUnexpectedEOF")
+	if _, ok := Classify(io.ErrUnexpectedEOF); ok {
+		t.Error("the builtin mapping should be removed")
+	}
+}
+
+func TestClassifyStd(t *testing.T) {
+	_, statErr := os.Stat(filepath.Join(t.TempDir(), "missing"))
+	tests := []struct {
+		err  error
+		want *Error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{sql.ErrTxDone, ErrTxDone},
+		{sql.ErrConnDone, ErrAlreadyClosed},
+		{fs.ErrNotExist, ErrNotFound},
+		{statErr, ErrNotFound},
+		{fs.ErrPermission, ErrPermission},
+		{fs.ErrExist, ErrConflict},
+		{context.DeadlineExceeded, ErrTimeout},
+		{os.ErrDeadlineExceeded, ErrTimeout},
+		{context.Canceled, ErrInterruptError},
+		{io.ErrUnexpectedEOF, ErrNetworkError},
+		{net.ErrClosed, ErrAlreadyClosed},
+	}
+	for _, test := range tests {
+		for _, err := range []error{test.err, fmt.Errorf("wrapped: %w", test.err)} {
+			if code, ok := Classify(err); !ok || code != test.want.Code {
+				t.Errorf("Classify(%v): got %d %v, want %d", err, code, ok, test.want.Code)
+			}
+			if code, _ := GetErrorCode(err); code != test.want.Code {
+				t.Errorf("GetErrorCode(%v): got %d", err, code)
+			}
+			if code, _ := GetHttpCode(err); code != test.want.HTTPCode() {
+				t.Errorf("GetHttpCode(%v): got %d", err, code)
+			}
+			if e := ToError(err); e.Code != test.want.Code || !stderrors.Is(e, err) {
+				t.Errorf("ToError(%v): got %d", err, e.Code)
+			}
+		}
+	}
+
+	// 有错误码的外层错误优先
+	if code, _ := GetErrorCode(Wrap(sql.ErrNoRows, "load")); code != ErrNotFound.Code {
+		t.Errorf("Wrap: got %d", code)
+	}
+	if code, _ := GetErrorCode(WithHTTPCode(sql.ErrNoRows, http.StatusGone)); ToHttpCode(code) != http.StatusGone {
+		t.Errorf("WithHTTPCode: got %d", code)
+	}
+	if _, ok := Classify(io.EOF); ok {
+		t.Error("io.EOF should not be classified")
+	}
+}
@@ -0,0 +1,117 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Collector 并发安全地收集多个 goroutine 中产生的错误
+//
+//	c := errors.NewCollector("同步失败")
+//	for _, item := range items {
+//		item := item
+//		c.Go(func() error { return sync(item) })
+//	}
+//	return c.Wait()
+type Collector struct {
+	// Title 多个错误时 ErrMultipleError 的消息
+	Title string
+	// MaxErrors 最多保留的错误个数, 0 表示不限制, 超出的错误被丢弃但会被计数
+	MaxErrors int
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	errs    []error
+	dropped int
+	cancel  context.CancelFunc
+}
+
+// NewCollector 创建一个 Collector
+func NewCollector(title string) *Collector {
+	return &Collector{Title: title}
+}
+
+// WithCancel 返回一个派生的 context, 第一个错误被添加时它会被取消, 必须在 Add 或 Go 之前调用
+func (c *Collector) WithCancel(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+	return ctx
+}
+
+// Add 添加一个错误, err 为 nil (包括值为 nil 的 *Error 等) 时什么也不做
+func (c *Collector) Add(err error) {
+	if isNilError(err) {
+		return
+	}
+
+	c.mu.Lock()
+	if c.MaxErrors > 0 && len(c.errs) >= c.MaxErrors {
+		c.dropped++
+	} else {
+		c.errs = append(c.errs, err)
+	}
+	cancel := c.cancel
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Go 在一个新的 goroutine 中执行 fn 并收集它返回的错误, fn 中的 panic 会被转换成错误
+func (c *Collector) Go(fn func() error) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		var err error
+		func() {
+			defer HandlePanic(&err, "collector")
+			err = fn()
+		}()
+		c.Add(err)
+	}()
+}
+
+// Wait 等待所有 Go 启动的 goroutine 结束, 然后返回 Err()
+func (c *Collector) Wait() error {
+	c.wg.Wait()
+
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return c.Err()
+}
+
+// Len 返回已收集的错误个数, 包括因 MaxErrors 被丢弃的
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errs) + c.dropped
+}
+
+// Errors 返回已收集的错误的副本
+func (c *Collector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.errs...)
+}
+
+// Err 没有错误时返回 nil, 只有一个错误时返回它本身, 否则返回一个 ErrMultipleError 的 *Error
+func (c *Collector) Err() error {
+	c.mu.Lock()
+	errs := append([]error(nil), c.errs...)
+	dropped := c.dropped
+	c.mu.Unlock()
+
+	if dropped > 0 {
+		errs = append(errs, fmt.Errorf("%d more errors are dropped", dropped))
+	}
+	return newMultiError(c.Title, errs)
+}
@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCollectorAddTypedNil(t *testing.T) {
+	c := NewCollector("failed")
+	var e *Error
+	c.Add(e)
+	c.Add(ToErrorIfNotNull(nil))
+	c.Add(io.EOF)
+
+	if c.Len() != 1 {
+		t.Errorf("Len: got %d, want 1", c.Len())
+	}
+	if err := c.Err(); err != io.EOF {
+		t.Errorf("Err: got %#v, want io.EOF", err)
+	}
+}
+
+func TestCollectorGo(t *testing.T) {
+	c := NewCollector("failed")
+	c.MaxErrors = 1
+	for i := 0; i < 3; i++ {
+		c.Go(func() error {
+			var e *Error
+			return e
+		})
+	}
+	c.Go(func() error { return io.EOF })
+	c.Go(func() error { return io.ErrUnexpectedEOF })
+	c.Go(func() error { panic("boom") })
+
+	err := c.Wait()
+	if c.Len() != 3 {
+		t.Errorf("Len: got %d, want 3", c.Len())
+	}
+	e := ToError(err)
+	if !e.isMultiple() || len(e.Errors()) != 2 {
+		t.Errorf("Err: got %#v", err)
+	}
+}
@@ -9,44 +9,51 @@ import (
 //)
 
 var (
-	ErrTimeout        = NewError(http.StatusGatewayTimeout*1000+1, "timeout")
-	ErrNotFound       = NewError(http.StatusNotFound*1000, "not found")
-	ErrFieldNotExists = NewError(http.StatusNotFound*1000+201, "field isnot found")
-	ErrKeyNotFound    = NewError(http.StatusNotFound*1000+501, "key isnot exists")
-	ErrRecordNotFound = NewError(http.StatusNotFound*1000+202, "record isnot found")
-	ErrValueNotFound  = NewError(http.StatusNotFound*1000+203, "value isnot found")
-	ErrDisabled       = NewError(http.StatusForbidden*1000+1, "disabled")
-	ErrNotAcceptable  = NewError(http.StatusNotAcceptable*1000+1, "not acceptable")
-	ErrNotImplemented = NewError(http.StatusNotImplemented*1000+1, "not implemented ")
+	ErrTimeout        = define(http.StatusGatewayTimeout*1000+1, "ErrTimeout", "timeout")
+	ErrNotFound       = define(http.StatusNotFound*1000, "ErrNotFound", "not found")
+	ErrFieldNotExists = define(http.StatusNotFound*1000+201, "ErrFieldNotExists", "field isnot found")
+	ErrKeyNotFound    = define(http.StatusNotFound*1000+501, "ErrKeyNotFound", "key isnot exists")
+	ErrRecordNotFound = define(http.StatusNotFound*1000+202, "ErrRecordNotFound", "record isnot found")
+	ErrValueNotFound  = define(http.StatusNotFound*1000+203, "ErrValueNotFound", "value isnot found")
+	ErrDisabled       = define(http.StatusForbidden*1000+1, "ErrDisabled", "disabled")
+	ErrNotAcceptable  = define(http.StatusNotAcceptable*1000+1, "ErrNotAcceptable", "not acceptable")
+	ErrNotImplemented = define(http.StatusNotImplemented*1000+1, "ErrNotImplemented", "not implemented ")
 	ErrUnimplemented  = ErrNotImplemented
-	ErrPending        = NewError(570*1000+1, "pending")
-	ErrRequired       = NewError(http.StatusBadRequest*1000+900, "required")
-	ErrPermission     = NewError(http.StatusUnauthorized*1000+101, "permission denied")
-	ErrUnauthorized   = NewError(http.StatusUnauthorized*1000+102, "user is unauthorized")
+	ErrPending        = define(570*1000+1, "ErrPending", "pending")
+	ErrRequired       = define(http.StatusBadRequest*1000+900, "ErrRequired", "required")
+	ErrPermission     = define(http.StatusUnauthorized*1000+101, "ErrPermission", "permission denied")
+	ErrUnauthorized   = define(http.StatusUnauthorized*1000+102, "ErrUnauthorized", "user is unauthorized")
 
-	ErrTypeError      = NewError(460*1000, "type error")
-	ErrValueNull      = NewError(461*1000, "value is null")
-	ErrNetworkError   = NewError(560000, "network error")
-	ErrInterruptError = NewError(561000, "interrupt error")
-	ErrMultipleError  = NewError(562000, "multiple error")
-	ErrTableNotExists = NewError(591000, "table isnot exists")
-	ErrResultEmpty    = NewError(592000, "results is empty")
-	ErrMultipleValues = NewError(http.StatusMultipleChoices*1000+000, "Multiple values meet the conditions")
-	ErrIDNotExists    = Required("id")
-	ErrBodyNotExists  = Required("body")
-	ErrBodyEmpty      = NewError(594000, "results is empty")
-	ErrAlreadyClosed  = NewError(595000, "already closed")
-	ErrAlreadyStart   = NewError(596000, "already start")
+	ErrTypeError      = define(460*1000, "ErrTypeError", "type error")
+	ErrValueNull      = define(461*1000, "ErrValueNull", "value is null")
+	ErrNetworkError   = define(560000, "ErrNetworkError", "network error")
+	ErrInterruptError = define(561000, "ErrInterruptError", "interrupt error")
+	ErrMultipleError  = define(562000, "ErrMultipleError", "multiple error")
+	ErrTableNotExists = define(591000, "ErrTableNotExists", "table isnot exists")
+	ErrResultEmpty    = define(592000, "ErrResultEmpty", "results is empty")
+	ErrMultipleValues = define(http.StatusMultipleChoices*1000+000, "ErrMultipleValues", "Multiple values meet the conditions")
+	ErrIDNotExists    = requiredSentinel("id")
+	ErrBodyNotExists  = requiredSentinel("body")
+	ErrBodyEmpty      = define(594000, "ErrBodyEmpty", "body is empty")
+	ErrAlreadyClosed  = define(595000, "ErrAlreadyClosed", "already closed")
+	ErrTxDone         = define(595001, "ErrTxDone", "transaction has already been committed or rolled back")
+	ErrAlreadyStart   = define(596000, "ErrAlreadyStart", "already start")
 
-	ErrReadResponseFail      = NewError(560011, "read response error")
-	ErrUnmarshalResponseFail = NewError(560012, "unmarshal response error")
+	ErrReadResponseFail      = define(560011, "ErrReadResponseFail", "read response error")
+	ErrUnmarshalResponseFail = define(560012, "ErrUnmarshalResponseFail", "unmarshal response error")
 
-	ErrBadArgument     = NewError(http.StatusBadRequest*1000, "bad argument")
+	ErrConnectionRefused = define(560101, "ErrConnectionRefused", "connection refused")
+	ErrConnectionReset   = define(560102, "ErrConnectionReset", "connection reset")
+	ErrDNSError          = define(560103, "ErrDNSError", "dns lookup failed")
+	ErrTLSError          = define(560104, "ErrTLSError", "tls handshake failed")
+	ErrHostUnreachable   = define(560105, "ErrHostUnreachable", "host is unreachable")
+
+	ErrBadArgument     = define(http.StatusBadRequest*1000, "ErrBadArgument", "bad argument")
 	ErrArgumentMissing = ErrRequired
-	ErrArgumentEmpty   = NewError(http.StatusBadRequest*1000+901, "empty")
-	ErrValidationError = NewError(http.StatusBadRequest*1000+902, "bad argument")
-	ErrNoContent       = NewError(http.StatusNoContent*1000+001, "no content")
-	ErrConflict        = NewError(http.StatusConflict*1000+001, "conflict")
+	ErrArgumentEmpty   = define(http.StatusBadRequest*1000+901, "ErrArgumentEmpty", "empty")
+	ErrValidationError = define(http.StatusBadRequest*1000+902, "ErrValidationError", "validation error")
+	ErrNoContent       = define(http.StatusNoContent*1000+001, "ErrNoContent", "no content")
+	ErrConflict        = define(http.StatusConflict*1000+001, "ErrConflict", "conflict")
 
 	ArgumentMissing    = ErrArgumentMissing
 	ArgumentEmpty      = ErrArgumentEmpty
@@ -12,6 +12,12 @@ func WithHTTPCode(err error, httpCode int) HTTPError {
 		panic(nerrors.New("err is nil"))
 	}
 	e := ToError(err, httpCode)
+	if e.isMultiple() {
+		// 保留多个错误的错误码和成员, 只改变它对外的 HTTP 状态
+		newErr := *e
+		newErr.status = ToHttpCode(httpCode)
+		return &newErr
+	}
 	e.Code = httpCode
 	return e
 }
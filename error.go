@@ -1,14 +1,9 @@
 package errors
 
 import (
-	"database/sql"
-	"encoding/json"
 	nerrors "errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
-	"strconv"
 	"strings"
 )
 
@@ -53,11 +48,19 @@ type Error struct {
 	Details   string              `json:"details,omitempty"`
 	Cause     error               `json:"-"`
 	Fields    map[string][]string `json:"data,omitempty"`
+	Values    Attrs               `json:"-"`
 	Internals []Error             `json:"internals,omitempty"`
+	Retry     *RetryHint          `json:"retry,omitempty"`
+
+	stack    []uintptr
+	status   int
+	template *messageTemplate
+	// matchMessage 为 true 时 Is 还要比较 Message, 用于 ErrIDNotExists 等与其它错误共用错误码的预定义错误
+	matchMessage bool
 }
 
 func (err *Error) Error() string {
-	if err.HTTPCode() == ErrMultipleError.HTTPCode() {
+	if err.isMultiple() {
 		var buffer strings.Builder
 		if err.Message != "" {
 			buffer.WriteString(err.Message)
@@ -81,11 +84,51 @@ func (err *Error) Unwrap() error {
 	return err.Cause
 }
 
+// Is 让 errors.Is 按错误码比较 *Error, 这样经过 ToResponseError, ReBuildFromError,
+// Concat 或按值复制后的错误仍能与 ErrNotFound 等预定义的错误匹配。
+// 只需要 HTTP 状态相同 (如跨服务的错误) 时用 IsSameHTTPStatus。
+// target 是 ErrIDNotExists 这类共用错误码的预定义错误时, 消息也要相同
+func (err *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t == nil {
+		return false
+	}
+	if err == t {
+		return true
+	}
+	if err.Code == 0 || t.Code == 0 {
+		return false
+	}
+	if err.Code != t.Code {
+		return false
+	}
+	return !t.matchMessage || err.Message == t.Message
+}
+
+// IsSameHTTPStatus 判断错误链中是否有与 target 的 HTTP 状态相同的错误, 适用于跨服务的错误,
+// 如远端只返回 404 时也能与 ErrRecordNotFound 匹配
+func IsSameHTTPStatus(err error, target *Error) bool {
+	if target == nil {
+		return false
+	}
+	return Walk(err, func(e error) bool {
+		hc, ok := e.(HTTPCoder)
+		return ok && hc.HTTPCode() == target.HTTPCode()
+	})
+}
+
 func (err *Error) ErrorCode() int {
 	return err.Code
 }
 
+// HTTPCode 返回错误码对应的 HTTP 状态, 多个错误按 SetMultiStatusPolicy 的设置由它的成员归纳
 func (err *Error) HTTPCode() int {
+	if err.status != 0 {
+		return err.status
+	}
+	if err.isMultiple() {
+		return err.multiStatus()
+	}
 	return ToHttpCode(err.Code)
 }
 
@@ -111,20 +154,37 @@ func RuntimeWrap(e error, s string, args ...interface{}) RuntimeError {
 
 	msg := fmt.Sprintf(s, args...) + ": " + e.Error()
 	if re, ok := e.(RuntimeError); ok {
-		return &ApplicationError{Cause: e, Code: re.ErrorCode(), Message: msg}
+		return &ApplicationError{Cause: e, Code: re.ErrorCode(), Message: msg, stack: captureStack(re.ErrorCode(), 1)}
 	}
 	if ec, ok := GetErrorCode(e); ok {
-		return &ApplicationError{Cause: e, Code: ec, Message: msg}
+		return &ApplicationError{Cause: e, Code: ec, Message: msg, stack: captureStack(ec, 1)}
 	}
 	if hc, ok := GetHttpCode(e); ok {
-		return &ApplicationError{Cause: e, Code: hc, Message: msg}
+		return &ApplicationError{Cause: e, Code: hc, Message: msg, stack: captureStack(hc, 1)}
 	}
 
-	if e == sql.ErrNoRows {
-		return &ApplicationError{Cause: e, Code: http.StatusNotFound, Message: msg}
-	}
+	return &ApplicationError{Cause: e, Code: http.StatusInternalServerError, Message: msg, stack: captureStack(http.StatusInternalServerError, 1)}
+}
 
-	return &ApplicationError{Cause: e, Code: http.StatusInternalServerError, Message: msg}
+// wrapCode 返回 Wrap 等为 err 创建的 *Error 的错误码, 即错误链中第一个 ErrorCoder 的错误码,
+// 或第一个 HTTPCoder 的 HTTP 状态, 都没有时用 Classify 确定
+func wrapCode(err error) (int, bool) {
+	var code int
+	found := Walk(err, func(e error) bool {
+		if ec, ok := e.(ErrorCoder); ok {
+			code = ec.ErrorCode()
+			return true
+		}
+		if hc, ok := e.(HTTPCoder); ok {
+			code = hc.HTTPCode()
+			return true
+		}
+		return false
+	})
+	if found {
+		return code, true
+	}
+	return Classify(err)
 }
 
 func Wrap(err error, msg string) error {
@@ -135,16 +195,20 @@ func Wrap(err error, msg string) error {
 		newErr := *he
 		newErr.Message = msg + ": " + he.Message
 		newErr.Cause = err
+		if len(newErr.stack) == 0 {
+			newErr.stack = captureStack(newErr.Code, 1)
+		}
 		return &newErr
 	}
-	if hc, ok := GetHttpCode(err); ok {
+	if code, ok := wrapCode(err); ok {
 		return &Error{
-			Code:    hc,
+			Code:    code,
 			Message: msg + ": " + err.Error(),
 			Cause:   err,
+			stack:   captureStack(code, 1),
 		}
 	}
-	return errwrap{err: err, msg: msg, mode: modePrefix}
+	return errwrap{err: err, msg: msg, mode: modePrefix, stack: stackRef(captureStack(http.StatusInternalServerError, 1))}
 }
 
 
@@ -156,16 +220,20 @@ func WrapWithMessage(err error, msg string) error {
 		newErr := *he
 		newErr.Message = msg
 		newErr.Cause = err
+		if len(newErr.stack) == 0 {
+			newErr.stack = captureStack(newErr.Code, 1)
+		}
 		return &newErr
 	}
-	if hc, ok := GetHttpCode(err); ok {
+	if code, ok := wrapCode(err); ok {
 		return &Error{
-			Code:    hc,
+			Code:    code,
 			Message: msg,
 			Cause:   err,
+			stack:   captureStack(code, 1),
 		}
 	}
-	return errwrap{err: err, msg: msg, mode: modeTitle}
+	return errwrap{err: err, msg: msg, mode: modeTitle, stack: stackRef(captureStack(http.StatusInternalServerError, 1))}
 }
 
 func Wrapf(err error, msg string, args ...interface{}) error {
@@ -180,16 +248,20 @@ func WrapWithSuffix(err error, msg string) error {
 		newErr := *he
 		newErr.Message = he.Message + ":" + msg
 		newErr.Cause = err
+		if len(newErr.stack) == 0 {
+			newErr.stack = captureStack(newErr.Code, 1)
+		}
 		return &newErr
 	}
-	if hc, ok := GetHttpCode(err); ok {
+	if code, ok := wrapCode(err); ok {
 		return &Error{
-			Code:    hc,
+			Code:    code,
 			Message: err.Error() + ": " + msg,
 			Cause:   err,
+			stack:   captureStack(code, 1),
 		}
 	}
-	return errwrap{err: err, msg: msg, mode: modeSuffix}
+	return errwrap{err: err, msg: msg, mode: modeSuffix, stack: stackRef(captureStack(http.StatusInternalServerError, 1))}
 }
 
 func New(msg string) error {
@@ -197,9 +269,11 @@ func New(msg string) error {
 }
 
 type errwrap struct {
-	err      error
-	msg      string
+	err  error
+	msg  string
 	mode int
+	// stack 放在指针后面, 以便 errwrap 仍然可以用 == 比较和作为 map 的键
+	stack *[]uintptr
 }
 
 const (
@@ -222,124 +296,47 @@ func (e errwrap) Unwrap() error {
 	return e.err
 }
 
-func ToResponseError(response *http.Response) error {
-	if response.Body == nil {
-		return NewRuntimeError(http.StatusNoContent, "no content")
-	}
-	contentType := response.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "text/plain") {
-		bs, _ := ioutil.ReadAll(response.Body)
-		if len(bs) == 0 {
-			return NewRuntimeError(response.StatusCode, response.Status)
-		}
-		return NewRuntimeError(response.StatusCode, string(bs))
-	}
-	var values map[string]interface{}
-	decoder := json.NewDecoder(response.Body)
-	decoder.UseNumber()
-	err := decoder.Decode(&values)
-	if err != nil {
-		return Wrap(err, "read error info")
-	}
-	defer io.Copy(io.Discard, response.Body)
-
-	var msg string
-	for _, key := range []string{"message", "error", "msg"} {
-		o := values[key]
-		if o == nil {
-			continue
-		}
-		msg, _ = o.(string)
-		if msg != "" {
-			break
+// GetErrorCode 返回错误链中第一个实现了 ErrorCoder 的错误的错误码
+//
+// 错误链按 Walk 的顺序遍历, 所以外层的错误优先于它包装的错误, 对于多个错误,
+// 排在前面的成员优先。错误链中没有 ErrorCoder 时用 Classify 确定错误码。
+func GetErrorCode(target error) (int, bool) {
+	var code int
+	found := Walk(target, func(err error) bool {
+		if ec, ok := err.(ErrorCoder); ok {
+			code = ec.ErrorCode()
+			return true
 		}
+		return false
+	})
+	if found {
+		return code, true
 	}
-	if msg == "" {
-		msg = fmt.Sprintf("%#v", values)
-	}
+	return Classify(target)
+}
 
-	e := &Error{
-		Code:    response.StatusCode,
-		Message: msg,
-		// Details   string              `json:"details,omitempty"`
-		// Cause     error               `json:"-"`
-		// Fields    map[string][]string `json:"data,omitempty"`
-		// Internals []Error             `json:"internals,omitempty"`
-	}
-	if len(values) > 0 {
-		if e.Fields == nil {
-			e.Fields = map[string][]string{}
+// GetHttpCode 返回错误链中第一个能确定 HTTP 状态的错误的 HTTP 状态
+//
+// 错误链按 Walk 的顺序遍历, 对于每一个错误, 依次检查 HTTPCoder 和 ErrorCoder,
+// 第一个满足的错误决定结果。都没有时用 Classify 确定错误码。
+func GetHttpCode(target error) (int, bool) {
+	var code int
+	found := Walk(target, func(err error) bool {
+		if hc, ok := err.(HTTPCoder); ok {
+			code = hc.HTTPCode()
+			return true
 		}
-		for key, value := range values {
-			if list, ok := value.([]string); ok {
-				ss := make([]string, len(list))
-				for idx := range list {
-					ss[idx] = fmt.Sprintf("%#v", list[idx])
-				}
-				e.Fields[key] = ss
-			} else {
-				e.Fields[key] = []string{fmt.Sprintf("%#v", value)}
-			}
+		if ec, ok := err.(ErrorCoder); ok {
+			code = ToHttpCode(ec.ErrorCode())
+			return true
 		}
+		return false
+	})
+	if found {
+		return code, true
 	}
-
-	if v := values["code"]; v != nil {
-		switch value := v.(type) {
-		case json.Number:
-			i, err := strconv.Atoi(value.String())
-			if err == nil {
-				e.Code = i
-			}
-		case int32:
-			e.Code = int(value)
-		case int64:
-			e.Code = int(value)
-		case int:
-			e.Code = value
-		case uint32:
-			e.Code = int(value)
-		case uint64:
-			e.Code = int(value)
-		case uint:
-			e.Code = int(value)
-		}
+	if ec, ok := Classify(target); ok {
+		return ToHttpCode(ec), true
 	}
-	return e
-}
-
-func GetErrorCode(target error) (int, bool) {
-  if target == nil {
-    return 0, false
-  }
-  wc, ok := target.(ErrorCoder)
-  if ok {
-    return wc.ErrorCode(), true
-  }
-  inner, ok := wc.(Wrapper)
-  if ok {
-    return GetErrorCode(inner.Unwrap())
-  }
-  return 0, false
+	return 0, false
 }
-
-func GetHttpCode(target error) (int, bool) {
-  if target == nil {
-    return 0, false
-  }
-  hc, ok := target.(HTTPCoder)
-  if ok {
-    return hc.HTTPCode(), true
-  }
-  wc, ok := target.(ErrorCoder)
-  if ok {
-    return ToHttpCode(wc.ErrorCode()), true
-  }
-  inner, ok := wc.(Wrapper)
-  if ok {
-    return GetHttpCode(inner.Unwrap())
-  }
-  if target == sql.ErrNoRows {
-		return http.StatusNotFound, true
-	}
-  return 0, false
-}
\ No newline at end of file
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIsByCode(t *testing.T) {
+	copied := *ErrRecordNotFound
+	if !Is(&copied, ErrRecordNotFound) {
+		t.Error("a copied error should match its sentinel")
+	}
+
+	bs, err := json.Marshal(ErrRecordNotFound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Error
+	if err := json.Unmarshal(bs, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !Is(&decoded, ErrRecordNotFound) {
+		t.Error("a decoded error should match its sentinel")
+	}
+	if Is(&decoded, ErrNotFound) {
+		t.Error("errors with the same HTTP status but different codes should not match")
+	}
+	if !Is(Wrap(&decoded, "load"), ErrRecordNotFound) {
+		t.Error("a wrapped error should match its sentinel")
+	}
+}
+
+func TestIsSameHTTPStatus(t *testing.T) {
+	remote := NewError(ErrNotFound.Code, "not found")
+	if Is(remote, ErrRecordNotFound) {
+		t.Error("Is should compare codes only")
+	}
+	if !IsSameHTTPStatus(Wrap(remote, "call"), ErrRecordNotFound) {
+		t.Error("IsSameHTTPStatus should compare HTTP status")
+	}
+	if IsSameHTTPStatus(remote, ErrBadArgument) {
+		t.Error("IsSameHTTPStatus should not match a different HTTP status")
+	}
+}
+
+func TestRequiredCodes(t *testing.T) {
+	if Is(ErrIDNotExists, ErrBodyNotExists) || Is(ErrBodyNotExists, ErrIDNotExists) {
+		t.Error("ErrIDNotExists and ErrBodyNotExists should not match each other")
+	}
+	if !Is(Required("id"), ErrIDNotExists) || Is(Required("name"), ErrIDNotExists) {
+		t.Error("Required should match ErrIDNotExists by message")
+	}
+	if Is(ErrNotFound, ErrIDNotExists) || !Is(ErrIDNotExists, ErrNotFound) {
+		t.Error("ErrIDNotExists should be narrower than ErrNotFound")
+	}
+	if code, _ := GetErrorCode(ErrIDNotExists); code != ErrNotFound.Code {
+		t.Errorf("code changed: %d", code)
+	}
+	var _ error = ErrIDNotExists
+	if ErrIDNotExists.Error() != Required("id").Error() || ErrBodyNotExists.Error() != Required("body").Error() {
+		t.Errorf("messages changed: %q, %q", ErrIDNotExists.Error(), ErrBodyNotExists.Error())
+	}
+	if HTTPCode(ErrIDNotExists) != 404 || HTTPCode(ErrBodyNotExists) != 404 {
+		t.Error("HTTP status changed")
+	}
+}
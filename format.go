@@ -0,0 +1,216 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var _ fmt.Formatter = &Error{}
+var _ fmt.Formatter = errwrap{}
+var _ fmt.Formatter = &WithSQL{}
+var _ fmt.Formatter = &withStack{}
+
+// Format 实现 fmt.Formatter
+//
+//	%s, %v  简短的消息, 与 Error() 相同
+//	%q      加引号的 Error()
+//	%+v     多行的完整报告, 包括错误码、HTTP 状态、Details、Fields、Internals、Cause 链和调用栈
+//	%#v     Go 语法的结构
+func (err *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, errorReport(err))
+			return
+		}
+		if s.Flag('#') {
+			fmt.Fprintf(s, "&errors.Error{Code:%d, Message:%q, Details:%q, Cause:%#v, Fields:%#v, Values:%#v, Internals:%#v}",
+				err.Code, err.Message, err.Details, err.Cause, err.Fields, err.Values, err.Internals)
+			return
+		}
+		io.WriteString(s, err.Error())
+	case 's':
+		io.WriteString(s, err.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", err.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errors.Error=%s)", verb, err.Error())
+	}
+}
+
+func (e errwrap) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			var sb strings.Builder
+			sb.WriteString(e.Error())
+			writeCause(&sb, e.err.Error(), e.err)
+			writeStack(&sb, e.frames())
+			io.WriteString(s, sb.String())
+			return
+		}
+		if s.Flag('#') {
+			fmt.Fprintf(s, "errors.errwrap{err:%#v, msg:%q, mode:%d}", e.err, e.msg, e.mode)
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(errors.errwrap=%s)", verb, e.Error())
+	}
+}
+
+// Format 实现 fmt.Formatter, %+v 输出发生 panic 时的调用栈
+func (w *withStack) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			var sb strings.Builder
+			sb.WriteString(w.Error())
+			writeStack(&sb, w.pcs)
+			io.WriteString(s, sb.String())
+			return
+		}
+		io.WriteString(s, w.Error())
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errors.withStack=%s)", verb, w.Error())
+	}
+}
+
+// Format 实现 fmt.Formatter, %+v 用 DefaultSQLFormatter 输出代入了参数 (敏感的参数被隐藏) 的 SQL,
+// %#v 中的参数也同样被隐藏
+func (w *WithSQL) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			var sb strings.Builder
+			sb.WriteString(w.Error())
+			sql, args := w.display()
+			sb.WriteString("\n  sql: ")
+			sb.WriteString(sql)
+			if len(args) > 0 {
+				fmt.Fprintf(&sb, "\n  args: %v", args)
+			}
+			writeCause(&sb, w.Err.Error(), w.Err)
+			io.WriteString(s, sb.String())
+			return
+		}
+		if s.Flag('#') {
+			fmt.Fprintf(s, "&errors.WithSQL{Err:%#v, SqlStr:%q, Args:%#v}", w.Err, w.SqlStr, w.goArgs())
+			return
+		}
+		io.WriteString(s, w.Error())
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errors.WithSQL=%s)", verb, w.Error())
+	}
+}
+
+func errorReport(err *Error) string {
+	var sb strings.Builder
+	sb.WriteString(err.Error())
+
+	sb.WriteString("\n  code: ")
+	fmt.Fprint(&sb, err.Code)
+	if info, ok := LookupCode(err.Code); ok {
+		sb.WriteString(" (")
+		sb.WriteString(info.Name)
+		sb.WriteString(")")
+	}
+	fmt.Fprintf(&sb, "\n  http status: %d", err.HTTPCode())
+
+	// Wrap 等函数会复制被包装的 *Error 并把它作为 Cause, 这时与 Cause 共享的内容只在 Cause 中输出
+	cause, _ := err.Cause.(*Error)
+	if err.Details != "" && (cause == nil || cause.Details != err.Details) {
+		sb.WriteString("\n  details: ")
+		sb.WriteString(indent(err.Details, "    "))
+	}
+	if len(err.Fields) > 0 && (cause == nil || !sameFields(cause.Fields, err.Fields)) {
+		sb.WriteString("\n  fields:")
+		keys := make([]string, 0, len(err.Fields))
+		for key := range err.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "\n    %s: %s", key, strings.Join(err.Fields[key], ", "))
+		}
+	}
+	if len(err.Values) > 0 && (cause == nil || !sameValues(cause.Values, err.Values)) {
+		sb.WriteString("\n  values:")
+		keys := make([]string, 0, len(err.Values))
+		for key := range err.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "\n    %s: %v", key, err.Values[key])
+		}
+	}
+	if err.Retry != nil && (cause == nil || cause.Retry != err.Retry) {
+		fmt.Fprintf(&sb, "\n  retryable: %t", err.Retry.Retryable)
+		if err.Retry.After > 0 {
+			fmt.Fprintf(&sb, " (after %s)", err.Retry.After)
+		}
+	}
+	if len(err.Internals) > 0 && (cause == nil || len(cause.Internals) == 0 || &cause.Internals[0] != &err.Internals[0]) {
+		sb.WriteString("\n  internals:")
+		for idx := range err.Internals {
+			sb.WriteString("\n    - ")
+			sb.WriteString(indent(errorReport(&err.Internals[idx]), "      "))
+		}
+	}
+	if _, ok := err.Cause.(errorList); !ok {
+		writeCause(&sb, err.Message, err.Cause)
+	}
+	if len(err.stack) > 0 && (cause == nil || len(cause.stack) == 0 || &cause.stack[0] != &err.stack[0]) {
+		writeStack(&sb, err.stack)
+	}
+	return sb.String()
+}
+
+func sameFields(a, b map[string][]string) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func sameValues(a, b Attrs) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// writeCause 输出 cause 的完整报告, 它与 msg 相同 (没有更多信息) 时忽略它
+func writeCause(sb *strings.Builder, msg string, cause error) {
+	if cause == nil {
+		return
+	}
+	report := fmt.Sprintf("%+v", cause)
+	if report == msg {
+		return
+	}
+	sb.WriteString("\n  cause: ")
+	sb.WriteString(indent(report, "  "))
+}
+
+func writeStack(sb *strings.Builder, pcs []uintptr) {
+	if len(pcs) == 0 {
+		return
+	}
+	sb.WriteString("\n  stack:")
+	sb.WriteString(indent(fmt.Sprintf("%+v", toStackTrace(pcs)), "    "))
+}
+
+func indent(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFormatShort(t *testing.T) {
+	e := NewError(ErrNotFound.Code, "user not found")
+	e.Details = "id=1"
+	for _, verb := range []string{"%s", "%v"} {
+		if s := fmt.Sprintf(verb, e); s != "user not found" {
+			t.Errorf("%s: got %q", verb, s)
+		}
+	}
+	if s := fmt.Sprintf("%q", e); s != `"user not found"` {
+		t.Errorf("%%q: got %s", s)
+	}
+	if s := fmt.Sprintf("%d", e); s != "%!d(*errors.Error=user not found)" {
+		t.Errorf("%%d: got %s", s)
+	}
+
+	w := Wrap(io.EOF, "read")
+	if s := fmt.Sprintf("%v", w); s != "read: EOF" {
+		t.Errorf("errwrap %%v: got %q", s)
+	}
+}
+
+func TestFormatReport(t *testing.T) {
+	withStackPolicy(t, StackNever)
+
+	e := NewError(ErrRecordNotFound.Code, "user not found")
+	e.Details = "line1\nline2"
+	e.WithValidationError("name", "required").WithValidationError("age", "too small")
+	e.Values = Attrs{"id": 1}
+	e.Retry = &RetryHint{Retryable: true}
+	e.Cause = &WithSQL{Err: io.EOF, SqlStr: "select * from users where id = ?", Args: []interface{}{1}}
+
+	s := fmt.Sprintf("%+v", e)
+	for _, want := range []string{
+		"user not found\n",
+		fmt.Sprintf("\n  code: %d (ErrRecordNotFound)", ErrRecordNotFound.Code),
+		"\n  http status: 404",
+		"\n  details: line1\n    line2",
+		"\n  fields:\n    age: too small\n    name: required",
+		"\n  values:\n    id: 1",
+		"\n  retryable: true",
+		"\n  cause: ",
+		"sql: select * from users where id = 1",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%%+v should contain %q, got:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "stack:") {
+		t.Errorf("%%+v should not print a stack when none is recorded:\n%s", s)
+	}
+
+	m := Concat(*NewError(ErrNotFound.Code, "a"), *NewError(ErrConflict.Code, "b"))
+	s = fmt.Sprintf("%+v", m)
+	if !strings.Contains(s, "\n  internals:\n    - a\n") || !strings.Contains(s, "\n    - b\n") {
+		t.Errorf("%%+v should list the internals, got:\n%s", s)
+	}
+
+	// Wrap 复制的内容只输出一次
+	w := Wrap(e, "load")
+	s = fmt.Sprintf("%+v", w)
+	if n := strings.Count(s, "details:"); n != 1 {
+		t.Errorf("details should be printed once, got %d:\n%s", n, s)
+	}
+	if n := strings.Count(s, "fields:"); n != 1 {
+		t.Errorf("fields should be printed once, got %d:\n%s", n, s)
+	}
+}
+
+func TestFormatGoSyntax(t *testing.T) {
+	e := &Error{Code: 400001, Message: "bad", Details: "d", Cause: io.EOF}
+	s := fmt.Sprintf("%#v", e)
+	want := `&errors.Error{Code:400001, Message:"bad", Details:"d", Cause:&errors.errorString{s:"EOF"}, Fields:map[string][]string(nil), Values:errors.Attrs(nil), Internals:[]errors.Error(nil)}`
+	if s != want {
+		t.Errorf("%%#v: got %s", s)
+	}
+
+	s = fmt.Sprintf("%#v", Wrap(io.EOF, "read"))
+	if s != `errors.errwrap{err:&errors.errorString{s:"EOF"}, msg:"read", mode:0}` {
+		t.Errorf("errwrap %%#v: got %s", s)
+	}
+}
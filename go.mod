@@ -1,6 +1,6 @@
 module github.com/runner-mei/errors
 
-go 1.18
+go 1.21
 
 require (
 	emperror.dev/emperror v0.33.0
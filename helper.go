@@ -1,18 +1,19 @@
 package errors
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
+	"os"
+	"reflect"
 	"strings"
-	"unicode"
-	"runtime/debug"
 
 	"emperror.dev/emperror"
 )
 
 func NewError(code int, msg string) *Error {
-	return &Error{Code: code, Message: msg}
+	return &Error{Code: code, Message: msg, stack: captureStack(code, 1)}
 }
 
 func NewApplicationError(code int, msg string) *Error {
@@ -63,7 +64,11 @@ func Join3(err1, err2, err3 error) error {
 }
 
 func Concat(list ...Error) *Error {
-	return &Error{Code: ErrMultipleError.ErrorCode(), Internals: list}
+	members := make(errorList, len(list))
+	for idx := range list {
+		members[idx] = &list[idx]
+	}
+	return &Error{Code: ErrMultipleError.ErrorCode(), Internals: list, Cause: members}
 }
 
 func ErrorIfNotEmpty(errList []error) error {
@@ -76,152 +81,191 @@ func ErrorIfNotEmpty(errList []error) error {
 	return ErrArray(errList)
 }
 
-func ErrArray(list ...interface{}) error {
-	var errList []Error
+// errorList 是多个错误的原始成员, 它作为多个错误的 Cause, 实现了 Unwrap() []error,
+// 所以 errors.Is 和 errors.As 能找到其中的任何一个成员
+type errorList []error
+
+func (list errorList) Error() string {
+	var buffer strings.Builder
+	for idx, e := range list {
+		if idx > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(e.Error())
+	}
+	return buffer.String()
+}
+
+func (list errorList) Unwrap() []error {
+	return list
+}
 
-	if len(list) == 0 {
+// Errors 返回多个错误的原始成员, 不是多个错误时返回 nil
+func (err *Error) Errors() []error {
+	if list, ok := err.Cause.(errorList); ok {
+		return list
+	}
+	if !err.isMultiple() || len(err.Internals) == 0 {
 		return nil
 	}
+	list := make([]error, len(err.Internals))
+	for idx := range err.Internals {
+		list[idx] = &err.Internals[idx]
+	}
+	return list
+}
 
-	var message string
-	for vidx, value := range list {
-		switch values := value.(type) {
-		case []interface{}:
-			if len(list) == 1 {
-				if len(values) == 0 {
-					return nil
-				}
-				if len(values) == 1 {
-					return values[0].(error)
-				}
-			}
-			if len(values) == 0 {
-				break
+// appendErrors 将 err 添加到 list 中, nil (包括值为 nil 的指针) 被忽略,
+// 多个错误 (ErrMultipleError 的 *Error 和 errors.Join 的结果等实现了 Unwrap() []error 的 error) 会被展开
+func appendErrors(list []error, err error) []error {
+	if isNilError(err) {
+		return list
+	}
+	if e, ok := err.(*Error); ok {
+		if members := e.Errors(); len(members) > 0 {
+			for _, m := range members {
+				list = appendErrors(list, m)
 			}
+			return list
+		}
+		return append(list, err)
+	}
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range u.Unwrap() {
+			list = appendErrors(list, e)
+		}
+		return list
+	}
+	return append(list, err)
+}
 
-			if errList == nil {
-				errList = make([]Error, 0, len(values))
-			}
-			for idx := range values {
-				errList = append(errList, *ToError(values[idx].(error)))
-			}
-		case []error:
-			if len(list) == 1 {
-				if len(values) == 0 {
-					return nil
-				}
-				if len(values) == 1 {
-					return values[0]
-				}
-			}
-			if len(values) == 0 {
-				break
-			}
+func isNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	rv := reflect.ValueOf(err)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
 
-			if errList == nil {
-				errList = make([]Error, 0, len(values))
-			}
-			for idx := range values {
-				errList = append(errList, *ToError(values[idx]))
-			}
+// Combine 将多个错误合并成一个, nil 被忽略, 嵌套的多个错误被展开。
+// 没有错误时返回 nil, 只有一个时返回它本身, 否则返回一个以 title 为消息的 ErrMultipleError 的 *Error。
+// title 为空且只有一个不为 nil 的参数时直接返回它, 这样已有的多个错误的标题不会丢失。
+func Combine[E error](title string, errs ...E) error {
+	if title == "" {
+		if err, ok := singleError(errs); ok {
+			return err
+		}
+	}
+	return newMultiError(title, appendSlice(nil, errs))
+}
+
+// CombineSlices 与 Combine 相同, 只是参数是多个错误切片
+func CombineSlices[E error](title string, lists ...[]E) error {
+	var list []E
+	for _, errs := range lists {
+		list = append(list, errs...)
+	}
+	return Combine(title, list...)
+}
+
+// singleError 返回 errs 中唯一的不为 nil 的错误
+func singleError[E error](errs []E) (error, bool) {
+	var found error
+	for _, e := range errs {
+		if isNilError(e) {
+			continue
+		}
+		if found != nil {
+			return nil, false
+		}
+		found = e
+	}
+	return found, found != nil
+}
+
+// newMultiError 用 errs 创建一个多个错误, errs 为空时返回 nil, 只有一个时返回它本身
+func newMultiError(message string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	internals := make([]Error, len(errs))
+	for idx, e := range errs {
+		internals[idx] = *ToError(e)
+	}
+	return &Error{
+		Code:      ErrMultipleError.ErrorCode(),
+		Message:   message,
+		Internals: internals,
+		Cause:     errorList(errs),
+	}
+}
+
+// ErrArray 将多个错误合并成一个, 参数可以是 error, []error, []interface{}, []HTTPError,
+// []Error, []*Error 或作为消息的 string, 其它的值用 fmt.Errorf("%v") 转换成错误。
+//
+// 它只是为了兼容而保留的, 新的代码请用类型安全的 Combine 和 CombineSlices。
+func ErrArray(list ...interface{}) error {
+	var errs []error
+	var message string
+	for _, value := range list {
+		switch values := value.(type) {
+		case nil:
+		case string:
+			message = values
+		case error:
+			errs = append(errs, values)
+		case []error:
+			errs = append(errs, values...)
 		case []HTTPError:
-			if len(list) == 1 {
-				if len(values) == 0 {
-					return nil
-				}
-				if len(values) == 1 {
-					return values[0]
-				}
-			}
-			if len(values) == 0 {
-				break
-			}
-			if errList == nil {
-				errList = make([]Error, 0, len(values))
-			}
-			for idx := range values {
-				errList = append(errList, *ToError(values[idx]))
-			}
+			errs = append(errs, Combine("", values...))
+		case []*Error:
+			errs = append(errs, Combine("", values...))
 		case []Error:
-			if len(list) == 1 {
-				if len(values) == 0 {
-					return nil
-				}
-				if len(values) == 1 {
-					return &values[0]
-				}
-			}
-			if len(values) == 0 {
-				break
-			}
-			if errList == nil {
-				errList = values
-			} else {
-				errList = append(errList, values...)
+			for idx := range values {
+				errs = append(errs, &values[idx])
 			}
-		case []*Error:
-			if len(list) == 1 {
-				if len(values) == 0 {
-					return nil
-				}
-				if len(values) == 1 {
-					return values[0]
+		case []interface{}:
+			for _, v := range values {
+				if e, ok := v.(error); ok {
+					errs = append(errs, e)
+				} else if v != nil {
+					errs = append(errs, fmt.Errorf("%v", v))
 				}
 			}
-			if len(values) == 0 {
-				break
-			}
-			if errList == nil {
-				errList = make([]Error, 0, len(values))
-			}
-
-			for _, err := range values {
-				errList = append(errList, *err)
-			}
 		default:
-			err, ok := value.(error)
-			if ok {
-				if len(list) == 1 {
-					return err
-				}
-				if errList == nil {
-					errList = make([]Error, 0, len(list))
-				}
-				errList = append(errList, *ToError(err))
-				break
-			}
-
-			msg, ok := value.(string)
-			if ok {
-				message = msg
-				break
-			}
-			panic(fmt.Errorf("list %d isnot error - %T", vidx, value))
+			errs = append(errs, fmt.Errorf("%v", value))
 		}
 	}
+	return Combine(message, errs...)
+}
 
-	if len(errList) == 0 {
-		return nil
-	}
-
-	if len(errList) == 1 {
-		return &errList[0]
+func appendSlice[E error](list []error, errs []E) []error {
+	for _, e := range errs {
+		list = appendErrors(list, e)
 	}
-	return &Error{Code: ErrMultipleError.ErrorCode(), Message: message, Internals: errList}
+	return list
 }
 
 func NewArgumentMissing(paramName string, err ...error) HTTPError {
-	if len(err) == 0 {
-		return &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is missing"}
-	}
-	return &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is missing"}
+	e := &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is missing"}
+	return e.WithTemplate("argument_missing", map[string]interface{}{"name": paramName})
 }
 
 func BadArgument(paramName string, value interface{}, err ...error) HTTPError {
 	if len(err) == 0 {
-		return &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is invalid"}
+		e := &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is invalid"}
+		return e.WithTemplate("bad_argument", map[string]interface{}{"name": paramName})
 	}
-	return &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is invalid - " + err[0].Error()}
+	e := &Error{Code: ErrBadArgument.ErrorCode(), Message: "param '" + paramName + "' is invalid - " + err[0].Error()}
+	return e.WithTemplate("bad_argument_error", map[string]interface{}{"name": paramName, "error": err[0].Error()})
 }
 
 func BadArgumentWithMessage(msg string, err ...error) *Error {
@@ -239,15 +283,18 @@ func NotFound(id interface{}, typ ...string) *Error {
 			return ErrNotFound
 		}
 
-		return NewError(ErrNotFound.Code, "record with id is '"+fmt.Sprint(id)+"' isn't found")
+		return NewError(ErrNotFound.Code, "record with id is '"+fmt.Sprint(id)+"' isn't found").
+			WithTemplate("not_found_id", map[string]interface{}{"id": id})
 	}
 
-	return NewError(ErrNotFound.Code, "record with type is '"+typ[0]+"' and id is '"+fmt.Sprint(id)+"' isn't found")
+	return NewError(ErrNotFound.Code, "record with type is '"+typ[0]+"' and id is '"+fmt.Sprint(id)+"' isn't found").
+		WithTemplate("not_found_type_id", map[string]interface{}{"type": typ[0], "id": id})
 }
 
 // NotFound 创建一个 ErrNotFound
 func ErrNotFoundWith(typeName string, id interface{}) *Error {
-	return NewError(ErrNotFound.Code, "record with type is '"+typeName+"' and id is '"+fmt.Sprint(id)+"' isn't found")
+	return NewError(ErrNotFound.Code, "record with type is '"+typeName+"' and id is '"+fmt.Sprint(id)+"' isn't found").
+		WithTemplate("not_found_type_id", map[string]interface{}{"type": typeName, "id": id})
 }
 
 // NotFound 创建一个 ErrNotFound
@@ -260,14 +307,21 @@ func ErrNotFoundWithText(msg string) *Error {
 }
 
 func RecordNotFound(id interface{}) error {
-	return NewError(ErrRecordNotFound.ErrorCode(), "'"+fmt.Sprint(id)+"' is not found.")
+	return NewError(ErrRecordNotFound.ErrorCode(), "'"+fmt.Sprint(id)+"' is not found.").
+		WithTemplate("record_not_found", map[string]interface{}{"id": id})
 }
 
+// GetDetails 返回错误链中第一个 DetailError 的 Details
 func GetDetails(err error) string {
-	if o, ok := err.(DetailError); ok {
-		return o.GetDetails()
-	}
-	return ""
+	var details string
+	Walk(err, func(e error) bool {
+		if o, ok := e.(DetailError); ok {
+			details = o.GetDetails()
+			return true
+		}
+		return false
+	})
+	return details
 }
 
 func IsUnauthorizedError(err error) bool {
@@ -294,14 +348,16 @@ func ToError(err error, defaultCode ...int) *Error {
 		result.Code = ec
 	} else if hc, ok := GetHttpCode(err); ok {
 		result.Code = hc
-	} else if err == sql.ErrNoRows {
-		result.Code = ErrNotFound.Code
 	}
 
 	for err != nil {
 		if x, ok := err.(interface{ Fill(*Error) }); ok {
 			x.Fill(result)
 		}
+		if he, ok := err.(*Error); ok && he.Retry != nil && result.Retry == nil {
+			hint := *he.Retry
+			result.Retry = &hint
+		}
 
 		u, ok := err.(interface{ Unwrap() error })
 		if !ok {
@@ -340,8 +396,6 @@ func HTTPCode(err error, statusCode ...int) int {
 
 	if hc, ok := GetHttpCode(err); ok {
 		code = hc
-	} else if err == sql.ErrNoRows {
-		code = http.StatusNotFound
 	}
 	return code
 }
@@ -359,18 +413,93 @@ func IsMultipleChoices(e error) bool {
 	return Is(e, ErrMultipleChoices)
 }
 
+// TimeoutMessageFallback 为 true 时, IsTimeoutError 在按类型检测失败后再按错误消息判断,
+// 消息中包含 TimeoutMessages 中的任何一个 (不区分大小写) 就认为是超时
+var TimeoutMessageFallback = false
+
+// TimeoutMessages 按消息判断超时错误时使用的关键字
+var TimeoutMessages = []string{"timeout", "time out", "timed out", "超时"}
+
 // IsTimeoutError 是不是一个超时错误
+//
+// 它遍历整个错误链, 满足下面任何一个条件就是超时错误:
+//
+//	是 context.DeadlineExceeded 或 os.ErrDeadlineExceeded
+//	实现了 Timeout() bool 并且返回 true, 如 net.Error 和 *url.Error
+//	错误码对应的 HTTP 状态为 504
+//
+// 遇到多个错误 (如 errors.Join 的结果) 时, 只有它的每一个成员都是超时错误时才是超时错误,
+// 这与 ShouldRetry 对多个错误的判断一致。只有 TimeoutMessageFallback 为 true 时才会按错误消息判断。
+// e 为 nil (包括值为 nil 的 *Error) 时返回 false。
 func IsTimeoutError(e error) bool {
-	if hc, ok := GetHttpCode(e); ok {
-		return hc == ErrTimeout.HTTPCode()
+	if isNilError(e) {
+		return false
+	}
+	if timeout, multiple := walkTimeout(e, 0); timeout || multiple {
+		return timeout
+	}
+	if code, ok := Classify(e); ok && ToHttpCode(code) == ErrTimeout.HTTPCode() {
+		return true
+	}
+	if TimeoutMessageFallback {
+		return isTimeoutMessage(e.Error())
 	}
+	return false
+}
 
-	s := e.Error()
-	if pos := strings.IndexFunc(s, unicode.IsSpace); pos > 0 {
-		se := s[pos+1:]
-		return se == "time out" || se == "timeout"
+// walkTimeout 沿错误链查找超时错误, 遇到多个错误时停止, 这时 multiple 为 true,
+// 只有每一个成员都是超时错误时 timeout 才为 true
+func walkTimeout(err error, depth int) (timeout bool, multiple bool) {
+	for err != nil && depth < maxChainDepth {
+		if isTimeout(err) {
+			return true, false
+		}
+		depth++
+
+		switch x := err.(type) {
+		case interface{ Unwrap() []error }:
+			members := x.Unwrap()
+			for _, member := range members {
+				if !IsTimeoutError(member) {
+					return false, true
+				}
+			}
+			return len(members) > 0, true
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Cause() error }:
+			err = x.Cause()
+		default:
+			return false, false
+		}
 	}
-	return s == "time out" || s == "timeout"
+	return false, false
+}
+
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded || err == os.ErrDeadlineExceeded {
+		return true
+	}
+	if t, ok := err.(interface{ Timeout() bool }); ok && t.Timeout() {
+		return true
+	}
+	if hc, ok := err.(HTTPCoder); ok {
+		return hc.HTTPCode() == ErrTimeout.HTTPCode()
+	}
+	if ec, ok := err.(ErrorCoder); ok {
+		return ToHttpCode(ec.ErrorCode()) == ErrTimeout.HTTPCode()
+	}
+	return false
+}
+
+func isTimeoutMessage(s string) bool {
+	s = strings.ToLower(s)
+	for _, keyword := range TimeoutMessages {
+		if strings.Contains(s, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
 }
 
 // IsNotFound 是不是一个未找到错误
@@ -401,7 +530,8 @@ func IsRecordNotFoundNotExists(err error) bool {
 
 func FieldNotExists(field string) error {
 	return NewError(ErrFieldNotExists.ErrorCode(), "field '"+field+"' is not exists").
-		WithValidationError("field", "Reqired")
+		WithValidationError("field", "Reqired").
+		WithTemplate("field_not_exists", map[string]interface{}{"field": field})
 }
 
 func IsFieldNotExists(err error) bool {
@@ -411,8 +541,19 @@ func IsFieldNotExists(err error) bool {
 	return false
 }
 
+// Required 返回一个 "'name' is required." 的错误, 它们的错误码都是 ErrNotFound 的,
+// 所以 errors.Is(err, ErrNotFound) 对任何 name 都成立。ErrIDNotExists 和 ErrBodyNotExists
+// 还会比较消息, 所以 errors.Is(Required("id"), ErrIDNotExists) 成立, 而与 ErrBodyNotExists 不匹配。
 func Required(name string) error {
-	return NewError(ErrNotFound.ErrorCode(), "'"+name+"' is required.")
+	return NewError(ErrNotFound.ErrorCode(), "'"+name+"' is required.").
+		WithTemplate("required", map[string]interface{}{"name": name})
+}
+
+// requiredSentinel 创建 ErrIDNotExists 这类 Required 的预定义错误, 它们在 Is 中还要比较消息
+func requiredSentinel(name string) error {
+	e := Required(name).(*Error)
+	e.matchMessage = true
+	return e
 }
 
 func IsTypeError(err error) bool {
@@ -436,11 +577,6 @@ func IsNoContent(err error) bool {
 	return false
 }
 
-func IsConnectError(err error) bool {
-	return strings.Contains(err.Error(), "connectex:") ||
-		strings.Contains(err.Error(), "connect:")
-}
-
 func IsStopped(e error) bool {
 	return e == ErrStopped
 }
@@ -483,21 +619,21 @@ func HandlePanic(err *error, context string) {
 			*err = fmt.Errorf("%s: %v", context, r)
 		}
 
-		stack := debug.Stack()
 		*err = &withStack{
 			err: *err,
-			stack: stack,
+			pcs: callers(2),
 		}
 	}
 }
 
+// withStack 是 HandlePanic 返回的错误, 调用栈只通过 StackTrace 和 %+v 输出, 不放在 Error() 中
 type withStack struct {
 	err error
-	stack []byte
+	pcs []uintptr
 }
 
-func (w *withStack) Error() string  {
-  return fmt.Sprintf("%s\r\n%s", w.err, w.stack)
+func (w *withStack) Error() string {
+	return w.err.Error()
 }
 
 func (w *withStack) Cause() error  { return w.err }
@@ -0,0 +1,222 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestErrArray(t *testing.T) {
+	var nilErr *Error
+	err := ErrArray("failed", io.EOF, nil, nilErr,
+		[]error{io.ErrUnexpectedEOF, nil},
+		[]*Error{ErrTimeout, nil},
+		[]HTTPError{ErrNotFound},
+		[]Error{*ErrConflict},
+		[]interface{}{io.ErrClosedPipe, "text", nil},
+		42)
+
+	e := ToError(err)
+	if !e.isMultiple() || e.Message != "failed" {
+		t.Fatalf("got %#v", err)
+	}
+	want := []string{
+		io.EOF.Error(),
+		io.ErrUnexpectedEOF.Error(),
+		ErrTimeout.Error(),
+		ErrNotFound.Error(),
+		ErrConflict.Error(),
+		io.ErrClosedPipe.Error(),
+		"text",
+		"42",
+	}
+	members := e.Errors()
+	if len(members) != len(want) {
+		t.Fatalf("got %d errors: %v", len(members), members)
+	}
+	for idx := range want {
+		if members[idx].Error() != want[idx] {
+			t.Errorf("%d: got %q, want %q", idx, members[idx].Error(), want[idx])
+		}
+	}
+	if !Is(err, ErrTimeout) || !Is(err, io.EOF) {
+		t.Errorf("Is: members are lost")
+	}
+}
+
+func TestErrArrayEmpty(t *testing.T) {
+	var nilErr *Error
+	if err := ErrArray("failed", nil, nilErr, []error{}); err != nil {
+		t.Errorf("got %#v, want nil", err)
+	}
+	if err := ErrArray("failed", io.EOF); err != io.EOF {
+		t.Errorf("got %#v, want io.EOF", err)
+	}
+}
+
+func TestErrArrayKeepsTitle(t *testing.T) {
+	m := Combine[error]("sync failed", io.EOF, ErrTimeout)
+	if got := ErrArray(m); got != m {
+		t.Errorf("ErrArray(m): got %#v, want m", got)
+	}
+	if got := ErrArray(nil, m, []error{nil}); got != m {
+		t.Errorf("ErrArray(nil, m): got %#v, want m", got)
+	}
+	if got := Combine("", m); got != m {
+		t.Errorf("Combine: got %#v, want m", got)
+	}
+	if got := CombineSlices("", []error{m}, nil); got != m {
+		t.Errorf("CombineSlices: got %#v, want m", got)
+	}
+	if !strings.HasPrefix(ErrArray(m, io.ErrUnexpectedEOF).Error(), "发生多个错误:") {
+		t.Error("merging with another error should flatten m")
+	}
+	e := ToError(ErrArray("retitled", m))
+	if e.Message != "retitled" || len(e.Errors()) != 2 {
+		t.Errorf("got %#v", e)
+	}
+}
+
+func TestIsTimeoutErrorMultiple(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{ErrTimeout, true},
+		{Wrap(context.DeadlineExceeded, "query"), true},
+		{stderrors.Join(ErrTimeout, ErrNotFound), false},
+		{stderrors.Join(context.DeadlineExceeded, ErrNotFound), false},
+		{fmt.Errorf("x: %w", stderrors.Join(ErrTimeout, context.DeadlineExceeded)), true},
+		{Combine[error]("", ErrTimeout, ErrNotFound), false},
+		{Combine[error]("", ErrTimeout, os.ErrDeadlineExceeded), true},
+	}
+	for _, test := range tests {
+		if got := IsTimeoutError(test.err); got != test.want {
+			t.Errorf("IsTimeoutError(%v): got %v", test.err, got)
+		}
+		if got := ShouldRetry(test.err); got != test.want {
+			t.Errorf("ShouldRetry(%v): got %v", test.err, got)
+		}
+	}
+}
+
+type timeoutError struct{ timeout bool }
+
+func (e timeoutError) Error() string   { return "i/o" }
+func (e timeoutError) Timeout() bool   { return e.timeout }
+func (e timeoutError) Temporary() bool { return false }
+
+func TestIsTimeoutError(t *testing.T) {
+	var nilErr *Error
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{io.EOF, false},
+		{ErrNotFound, false},
+		{context.DeadlineExceeded, true},
+		{os.ErrDeadlineExceeded, true},
+		{fmt.Errorf("read: %w", os.ErrDeadlineExceeded), true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{true}}, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{false}}, false},
+		{&url.Error{Op: "Get", URL: "http://a", Err: timeoutError{true}}, true},
+		{&url.Error{Op: "Get", URL: "http://a", Err: io.EOF}, false},
+		{&net.DNSError{Err: "i/o timeout", Name: "a", IsTimeout: true}, true},
+		{NewError(http.StatusGatewayTimeout, "gateway timeout"), true},
+		{Wrap(timeoutError{true}, "call"), true},
+		{New("connection timed out"), false},
+	}
+	for _, test := range tests {
+		if got := IsTimeoutError(test.err); got != test.want {
+			t.Errorf("IsTimeoutError(%v): got %v", test.err, got)
+		}
+	}
+	if IsTimeoutError(nilErr) {
+		t.Error("a nil *Error should not be a timeout")
+	}
+}
+
+func TestIsTimeoutErrorMessageFallback(t *testing.T) {
+	old := TimeoutMessageFallback
+	TimeoutMessageFallback = true
+	t.Cleanup(func() { TimeoutMessageFallback = old })
+
+	for _, s := range []string{"connection timed out", "Read Timeout", "请求超时"} {
+		if !IsTimeoutError(New(s)) {
+			t.Errorf("%q should be a timeout", s)
+		}
+	}
+	if IsTimeoutError(New("connection refused")) {
+		t.Error("connection refused should not be a timeout")
+	}
+}
+
+type pathError struct{ path string }
+
+func (e *pathError) Error() string { return "bad path " + e.path }
+
+func TestJoinUnwrap(t *testing.T) {
+	pe := &pathError{path: "/a"}
+	tests := []error{
+		Join(io.EOF, pe),
+		Join3(ErrNotFound, io.EOF, pe),
+		Concat(*ErrNotFound, *ToError(io.EOF)),
+		ErrArray("failed", io.EOF, []error{pe}),
+		Combine[error]("failed", io.EOF, pe),
+		Wrap(Join(io.EOF, pe), "outer"),
+	}
+	for _, err := range tests {
+		if !stderrors.Is(err, io.EOF) {
+			t.Errorf("errors.Is(%q, io.EOF): got false", err)
+		}
+		if !Is(err, io.EOF) {
+			t.Errorf("Is(%q, io.EOF): got false", err)
+		}
+		if stderrors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("errors.Is(%q, io.ErrUnexpectedEOF): got true", err)
+		}
+	}
+	for _, err := range []error{tests[0], tests[1], tests[3], tests[4], tests[5]} {
+		var target *pathError
+		if !stderrors.As(err, &target) || target != pe {
+			t.Errorf("errors.As(%q): got %v", err, target)
+		}
+	}
+	if !stderrors.Is(tests[1], ErrNotFound) {
+		t.Error("errors.Is should find an *Error member")
+	}
+	if e := tests[0].(*Error); len(e.Errors()) != 2 || e.Errors()[0] != io.EOF {
+		t.Errorf("Errors: got %#v", e.Errors())
+	}
+}
+
+func TestJoinJSON(t *testing.T) {
+	err := Join(NewError(ErrNotFound.Code, "a"), io.EOF)
+	bs, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := fmt.Sprintf(`{"code":%d,"message":"","internals":[{"code":%d,"message":"a"},{"code":%d,"message":"EOF"}]}`,
+		ErrMultipleError.Code, ErrNotFound.Code, ToError(io.EOF).Code)
+	if string(bs) != want {
+		t.Errorf("got  %s\nwant %s", bs, want)
+	}
+
+	// 反序列化后仍然是多个错误
+	var decoded Error
+	if e := json.Unmarshal(bs, &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if !decoded.isMultiple() || len(decoded.Internals) != 2 || decoded.Internals[0].Code != ErrNotFound.Code {
+		t.Errorf("decoded: got %#v", decoded)
+	}
+}
@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// MultiStatusPolicy 根据多个错误的各个成员的 HTTP 状态确定多个错误对外的 HTTP 状态,
+// statuses 至少有一个元素
+type MultiStatusPolicy func(statuses []int) int
+
+var (
+	// MultiStatusLegacy 总是返回 ErrMultipleError 的状态 (562), 这是默认值
+	MultiStatusLegacy MultiStatusPolicy = func(statuses []int) int {
+		return ToHttpCode(ErrMultipleError.Code)
+	}
+
+	// MultiStatusDerived 成员的状态都相同时返回它, 都是 4xx 时返回 400,
+	// 有 5xx 时返回 500, 否则返回 ErrMultipleError 的状态 (562)
+	MultiStatusDerived = DeriveMultiStatus(ToHttpCode(ErrMultipleError.Code))
+
+	// MultiStatusDerived207 与 MultiStatusDerived 相同, 只是无法归纳时返回 207 Multi-Status
+	MultiStatusDerived207 = DeriveMultiStatus(http.StatusMultiStatus)
+)
+
+// DeriveMultiStatus 返回一个按成员的状态归纳的 MultiStatusPolicy, 无法归纳时返回 fallback
+func DeriveMultiStatus(fallback int) MultiStatusPolicy {
+	return func(statuses []int) int {
+		same, all4xx, any5xx := true, true, false
+		for _, status := range statuses {
+			if status != statuses[0] {
+				same = false
+			}
+			if status < 400 || status > 499 {
+				all4xx = false
+			}
+			if status >= 500 && status <= 599 {
+				any5xx = true
+			}
+		}
+		switch {
+		case same:
+			return statuses[0]
+		case all4xx:
+			return http.StatusBadRequest
+		case any5xx:
+			return http.StatusInternalServerError
+		}
+		return fallback
+	}
+}
+
+var multiStatusPolicy atomic.Value
+
+// SetMultiStatusPolicy 设置多个错误的 HTTP 状态的归纳方式, policy 为 nil 时恢复成 MultiStatusLegacy
+func SetMultiStatusPolicy(policy MultiStatusPolicy) {
+	if policy == nil {
+		policy = MultiStatusLegacy
+	}
+	multiStatusPolicy.Store(policy)
+}
+
+// GetMultiStatusPolicy 返回多个错误的 HTTP 状态的归纳方式
+func GetMultiStatusPolicy() MultiStatusPolicy {
+	if policy, ok := multiStatusPolicy.Load().(MultiStatusPolicy); ok {
+		return policy
+	}
+	return MultiStatusLegacy
+}
+
+// isMultiple 判断 err 是否是一个多个错误
+func (err *Error) isMultiple() bool {
+	return ToHttpCode(err.Code) == ToHttpCode(ErrMultipleError.Code)
+}
+
+// multiStatus 用 GetMultiStatusPolicy() 归纳多个错误的 HTTP 状态, 没有成员时返回 562
+func (err *Error) multiStatus() int {
+	if len(err.Internals) == 0 {
+		return ToHttpCode(err.Code)
+	}
+	statuses := make([]int, len(err.Internals))
+	for idx := range err.Internals {
+		statuses[idx] = err.Internals[idx].HTTPCode()
+	}
+	status := GetMultiStatusPolicy()(statuses)
+	if status <= 0 {
+		return ToHttpCode(err.Code)
+	}
+	return status
+}
@@ -0,0 +1,72 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func withMultiStatusPolicy(t *testing.T, policy MultiStatusPolicy) {
+	old := GetMultiStatusPolicy()
+	SetMultiStatusPolicy(policy)
+	t.Cleanup(func() { SetMultiStatusPolicy(old) })
+}
+
+func TestMultiStatusPolicy(t *testing.T) {
+	same := Combine[error]("", ErrNotFound, ErrRecordNotFound)
+	client := Combine[error]("", ErrNotFound, ErrConflict)
+	server := Combine[error]("", ErrNotFound, ErrTimeout)
+	mixed := Combine[error]("", ErrNotFound, NewError(http.StatusFound, "moved"))
+
+	tests := []struct {
+		name   string
+		policy MultiStatusPolicy
+		want   []int // same, client, server, mixed
+	}{
+		{"legacy", MultiStatusLegacy, []int{562, 562, 562, 562}},
+		{"nil", nil, []int{562, 562, 562, 562}},
+		{"derived", MultiStatusDerived, []int{404, 400, 500, 562}},
+		{"derived207", MultiStatusDerived207, []int{404, 400, 500, 207}},
+		{"custom", func(statuses []int) int { return statuses[len(statuses)-1] }, []int{404, 409, 504, 302}},
+	}
+	for _, test := range tests {
+		withMultiStatusPolicy(t, test.policy)
+
+		for idx, err := range []error{same, client, server, mixed} {
+			if got := HTTPCode(err); got != test.want[idx] {
+				t.Errorf("%s: HTTPCode(%d) got %d, want %d", test.name, idx, got, test.want[idx])
+			}
+
+			w := httptest.NewRecorder()
+			WriteError(w, httptest.NewRequest(http.MethodGet, "/", nil), err)
+			if w.Code != test.want[idx] {
+				t.Errorf("%s: WriteError(%d) status got %d, want %d", test.name, idx, w.Code, test.want[idx])
+			}
+			// 错误码仍然是 ErrMultipleError 的
+			if w.Header().Get(ErrorCodeHeader) != strconv.Itoa(ErrMultipleError.Code) {
+				t.Errorf("%s: %s got %q", test.name, ErrorCodeHeader, w.Header().Get(ErrorCodeHeader))
+			}
+			if code, _ := GetErrorCode(ToResponseError(w.Result())); code != ErrMultipleError.Code {
+				t.Errorf("%s: round trip code got %d", test.name, code)
+			}
+		}
+	}
+}
+
+func TestMultiStatusOverride(t *testing.T) {
+	withMultiStatusPolicy(t, MultiStatusDerived)
+
+	err := WithHTTPCode(Combine[error]("", ErrNotFound, ErrConflict), http.StatusUnprocessableEntity)
+	if got := HTTPCode(err); got != http.StatusUnprocessableEntity {
+		t.Errorf("WithHTTPCode: got %d", got)
+	}
+	if e := ToError(err); e.Code != ErrMultipleError.Code || len(e.Internals) != 2 {
+		t.Errorf("WithHTTPCode should keep the members: %#v", e)
+	}
+
+	// 没有成员时使用 ErrMultipleError 的状态
+	if got := (&Error{Code: ErrMultipleError.Code}).HTTPCode(); got != 562 {
+		t.Errorf("no members: got %d", got)
+	}
+}
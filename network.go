@@ -0,0 +1,113 @@
+package errors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"syscall"
+)
+
+// ClassifyNetworkError 将网络错误分类成 ErrNetworkError 的子错误:
+//
+//	ErrConnectionRefused  连接被拒绝 (ECONNREFUSED)
+//	ErrConnectionReset    连接被重置或断开 (ECONNRESET, ECONNABORTED, EPIPE)
+//	ErrDNSError           域名解析失败 (*net.DNSError)
+//	ErrTLSError           TLS 握手或证书校验失败
+//	ErrHostUnreachable    主机或网络不可达 (EHOSTUNREACH, ENETUNREACH)
+//	ErrNetworkError       其它的 *net.OpError
+//
+// 它按错误链查找, 所以 *url.Error 等包装过的错误也能识别, 不是网络错误时返回 false。
+func ClassifyNetworkError(err error) (*Error, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var result *Error
+	Walk(err, func(e error) bool {
+		switch x := e.(type) {
+		case *net.DNSError:
+			result = ErrDNSError
+		case *tls.CertificateVerificationError, tls.RecordHeaderError, *tls.RecordHeaderError, tls.AlertError,
+			x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError,
+			*x509.UnknownAuthorityError, *x509.HostnameError, *x509.CertificateInvalidError:
+			result = ErrTLSError
+		case syscall.Errno:
+			switch {
+			case isErrno(x, refusedErrnos):
+				result = ErrConnectionRefused
+			case isErrno(x, resetErrnos):
+				result = ErrConnectionReset
+			case isErrno(x, unreachableErrnos):
+				result = ErrHostUnreachable
+			}
+		}
+		return result != nil
+	})
+	if result != nil {
+		return result, true
+	}
+
+	var opErr *net.OpError
+	if As(err, &opErr) {
+		return ErrNetworkError, true
+	}
+	return nil, false
+}
+
+func isErrno(errno syscall.Errno, list []error) bool {
+	for _, target := range list {
+		if errno == target {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConnectError 是不是一个建立连接时的错误, 包括连接被拒绝、主机不可达、域名解析失败、
+// TLS 握手失败和其它的 dial 错误, 连接建立后被重置不算
+func IsConnectError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if e, ok := ClassifyNetworkError(err); ok {
+		switch e {
+		case ErrConnectionRefused, ErrHostUnreachable, ErrDNSError, ErrTLSError:
+			return true
+		}
+	}
+
+	var opErr *net.OpError
+	if As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	if ec, ok := GetErrorCode(err); ok {
+		switch ec {
+		case ErrConnectionRefused.Code, ErrHostUnreachable.Code, ErrDNSError.Code, ErrTLSError.Code:
+			return true
+		}
+	}
+	return false
+}
+
+// IsNetworkError 是不是一个网络错误, 包括 ClassifyNetworkError 能识别的错误和
+// HTTP 状态与 ErrNetworkError 相同的错误
+func IsNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := ClassifyNetworkError(err); ok {
+		return true
+	}
+	hc, ok := GetHttpCode(err)
+	return ok && hc == ErrNetworkError.HTTPCode()
+}
+
+func init() {
+	RegisterClassifier("net", BuiltinClassifierPriority, func(err error) (int, bool) {
+		if e, ok := ClassifyNetworkError(err); ok {
+			return e.Code, true
+		}
+		return 0, false
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows && !js && !wasip1
+
+package errors
+
+// 其它的平台 (如 plan9) 没有这些 errno, 只能按 *net.OpError 识别成 ErrNetworkError
+var (
+	refusedErrnos     []error
+	resetErrnos       []error
+	unreachableErrnos []error
+)
@@ -0,0 +1,73 @@
+package errors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestClassifyNetworkError(t *testing.T) {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "nonexistent.invalid", IsNotFound: true}
+	tests := []struct {
+		err  error
+		want *Error
+	}{
+		{dnsErr, ErrDNSError},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: dnsErr}, ErrDNSError},
+		{&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, ErrTLSError},
+		{x509.HostnameError{Host: "a"}, ErrTLSError},
+		{&x509.CertificateInvalidError{Reason: x509.Expired}, ErrTLSError},
+		{tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, ErrTLSError},
+		{&tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, ErrTLSError},
+		{tls.AlertError(40), ErrTLSError},
+		{&net.OpError{Op: "read", Net: "tcp", Err: io.EOF}, ErrNetworkError},
+	}
+	for _, test := range tests {
+		for _, err := range []error{
+			test.err,
+			&url.Error{Op: "Get", URL: "https://a", Err: test.err},
+			fmt.Errorf("call: %w", test.err),
+		} {
+			got, ok := ClassifyNetworkError(err)
+			if !ok || got != test.want {
+				t.Errorf("ClassifyNetworkError(%v): got %v %v, want %v", err, got, ok, test.want)
+			}
+			if code, _ := GetErrorCode(err); code != test.want.Code {
+				t.Errorf("GetErrorCode(%v): got %d", err, code)
+			}
+			if !IsNetworkError(err) {
+				t.Errorf("IsNetworkError(%v): got false", err)
+			}
+		}
+	}
+
+	for _, err := range []error{nil, io.EOF, ErrNotFound, &url.Error{Op: "Get", URL: "https://a", Err: io.EOF}} {
+		if got, ok := ClassifyNetworkError(err); ok {
+			t.Errorf("ClassifyNetworkError(%v): got %v", err, got)
+		}
+	}
+}
+
+func TestClassifyNetworkErrorDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	_, err = net.Dial("tcp", addr)
+	if err == nil {
+		t.Skip("the closed port accepted a connection")
+	}
+	if got, ok := ClassifyNetworkError(err); !ok || got != ErrConnectionRefused {
+		t.Errorf("ClassifyNetworkError(%v): got %v %v", err, got, ok)
+	}
+	if !IsConnectError(err) {
+		t.Errorf("IsConnectError(%v): got false", err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build unix || js || wasip1
+
+package errors
+
+import "syscall"
+
+var (
+	refusedErrnos = []error{
+		syscall.ECONNREFUSED,
+	}
+	resetErrnos = []error{
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.EPIPE,
+	}
+	unreachableErrnos = []error{
+		syscall.EHOSTUNREACH,
+		syscall.ENETUNREACH,
+	}
+)
@@ -0,0 +1,42 @@
+//go:build unix
+
+package errors
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyNetworkErrno(t *testing.T) {
+	tests := []struct {
+		errno   syscall.Errno
+		want    *Error
+		connect bool
+	}{
+		{syscall.ECONNREFUSED, ErrConnectionRefused, true},
+		{syscall.ECONNRESET, ErrConnectionReset, false},
+		{syscall.ECONNABORTED, ErrConnectionReset, false},
+		{syscall.EPIPE, ErrConnectionReset, false},
+		{syscall.EHOSTUNREACH, ErrHostUnreachable, true},
+		{syscall.ENETUNREACH, ErrHostUnreachable, true},
+		{syscall.EACCES, ErrNetworkError, false},
+	}
+	for _, test := range tests {
+		op := "read"
+		if test.connect {
+			op = "connect"
+		}
+		opErr := &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, test.errno)}
+		err := &url.Error{Op: "Get", URL: "http://a", Err: opErr}
+
+		if got, ok := ClassifyNetworkError(err); !ok || got != test.want {
+			t.Errorf("%v: got %v %v, want %v", test.errno, got, ok, test.want)
+		}
+		if got := IsConnectError(err); got != test.connect {
+			t.Errorf("IsConnectError(%v): got %v", test.errno, got)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package errors
+
+import "syscall"
+
+var (
+	refusedErrnos = []error{
+		syscall.ECONNREFUSED,
+		syscall.Errno(10061), // WSAECONNREFUSED
+	}
+	resetErrnos = []error{
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.EPIPE,
+		syscall.Errno(10053), // WSAECONNABORTED
+		syscall.Errno(10054), // WSAECONNRESET
+	}
+	unreachableErrnos = []error{
+		syscall.EHOSTUNREACH,
+		syscall.ENETUNREACH,
+		syscall.Errno(10051), // WSAENETUNREACH
+		syscall.Errno(10065), // WSAEHOSTUNREACH
+	}
+)
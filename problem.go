@@ -0,0 +1,198 @@
+package errors
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType RFC 7807 / RFC 9457 规定的 Problem Details 的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix 生成 Problem 的 type 成员时使用的前缀, 后面跟着错误码
+var ProblemTypePrefix = "urn:runner-mei:errors:"
+
+// Problem 是 RFC 7807 / RFC 9457 中的 Problem Details 对象
+//
+// 它与 *Error 的对应关系如下:
+//
+//	type      ProblemTypePrefix + Code
+//	title     Message
+//	status    HTTPCode()
+//	detail    Details
+//	instance  由调用者提供
+//	code      Code (扩展成员)
+//	data      Fields (扩展成员)
+//	attrs     Values (扩展成员)
+//	internals Internals (扩展成员)
+//	retry     Retry (扩展成员)
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+var problemMembers = []string{"type", "title", "status", "detail", "instance"}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	values := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		values[key] = value
+	}
+	if p.Type != "" {
+		values["type"] = p.Type
+	}
+	if p.Title != "" {
+		values["title"] = p.Title
+	}
+	if p.Status != 0 {
+		values["status"] = p.Status
+	}
+	if p.Detail != "" {
+		values["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		values["instance"] = p.Instance
+	}
+	return json.Marshal(values)
+}
+
+func (p *Problem) UnmarshalJSON(bs []byte) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(bs, &values); err != nil {
+		return err
+	}
+
+	*p = Problem{}
+	for _, key := range problemMembers {
+		raw, ok := values[key]
+		if !ok {
+			continue
+		}
+		delete(values, key)
+
+		var err error
+		switch key {
+		case "type":
+			err = json.Unmarshal(raw, &p.Type)
+		case "title":
+			err = json.Unmarshal(raw, &p.Title)
+		case "status":
+			err = json.Unmarshal(raw, &p.Status)
+		case "detail":
+			err = json.Unmarshal(raw, &p.Detail)
+		case "instance":
+			err = json.Unmarshal(raw, &p.Instance)
+		}
+		if err != nil {
+			return Wrap(err, "unmarshal problem member '"+key+"'")
+		}
+	}
+
+	if len(values) > 0 {
+		p.Extensions = make(map[string]interface{}, len(values))
+		for key, raw := range values {
+			p.Extensions[key] = raw
+		}
+	}
+	return nil
+}
+
+// ToProblem 将 err 转换成 Problem Details, instance 可以为空
+func ToProblem(err error, instance string) *Problem {
+	e := ToError(err)
+	p := &Problem{
+		Type:     ProblemTypePrefix + strconv.Itoa(e.Code),
+		Title:    e.Message,
+		Status:   e.HTTPCode(),
+		Detail:   e.Details,
+		Instance: instance,
+		Extensions: map[string]interface{}{
+			"code": e.Code,
+		},
+	}
+	if len(e.Fields) > 0 {
+		p.Extensions["data"] = e.Fields
+	}
+	if len(e.Values) > 0 {
+		p.Extensions["attrs"] = e.Values
+	}
+	if len(e.Internals) > 0 {
+		p.Extensions["internals"] = e.Internals
+	}
+	if e.Retry != nil {
+		p.Extensions["retry"] = e.Retry
+	}
+	if p.Title == "" {
+		if info, ok := LookupCode(e.Code); ok {
+			p.Title = info.Message
+		}
+	}
+	return p
+}
+
+// ToError 将 Problem Details 转换回 *Error
+func (p *Problem) ToError() *Error {
+	e := &Error{
+		Code:    p.Status,
+		Message: p.Title,
+		Details: p.Detail,
+	}
+
+	if strings.HasPrefix(p.Type, ProblemTypePrefix) {
+		if code, err := strconv.Atoi(strings.TrimPrefix(p.Type, ProblemTypePrefix)); err == nil {
+			e.Code = code
+		}
+	}
+
+	if v, ok := p.Extensions["code"]; ok {
+		var code int
+		if problemExtension(v, &code) {
+			e.Code = code
+		}
+	}
+	if v, ok := p.Extensions["data"]; ok {
+		var data map[string]json.RawMessage
+		if problemExtension(v, &data) {
+			e.setData(data)
+		}
+	}
+	if v, ok := p.Extensions["attrs"]; ok {
+		var attrs map[string]json.RawMessage
+		if problemExtension(v, &attrs) {
+			e.setAttrs(attrs)
+		}
+	}
+	if v, ok := p.Extensions["internals"]; ok {
+		problemExtension(v, &e.Internals)
+	}
+	if v, ok := p.Extensions["retry"]; ok {
+		var hint RetryHint
+		if problemExtension(v, &hint) {
+			e.Retry = &hint
+		}
+	}
+
+	if e.Message == "" {
+		e.Message = p.Detail
+		e.Details = ""
+	}
+	return e
+}
+
+// problemExtension 将扩展成员转换到 target 中, 扩展成员可能是解码得到的 json.RawMessage,
+// 也可能是调用者直接放入的 Go 值
+func problemExtension(value interface{}, target interface{}) bool {
+	raw, ok := value.(json.RawMessage)
+	if !ok {
+		bs, err := json.Marshal(value)
+		if err != nil {
+			return false
+		}
+		raw = bs
+	}
+	return json.Unmarshal(raw, target) == nil
+}
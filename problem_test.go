@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProblemMarshalByValue(t *testing.T) {
+	p := ToProblem(NotFound(42), "/users/42")
+
+	for name, v := range map[string]interface{}{
+		"pointer": p,
+		"value":   *p,
+		"field":   struct{ Problem Problem }{Problem: *p},
+	} {
+		bs, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(bs, &values); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if name == "field" {
+			values, _ = values["Problem"].(map[string]interface{})
+		}
+		if values["type"] != p.Type || values["status"] != float64(404) || values["instance"] != "/users/42" {
+			t.Errorf("%s: got %s", name, bs)
+		}
+		if _, ok := values["Extensions"]; ok {
+			t.Errorf("%s: Extensions should be inlined, got %s", name, bs)
+		}
+	}
+}
+
+func TestProblemRoundTrip(t *testing.T) {
+	bs, err := json.Marshal(ToProblem(ErrValidationError, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Problem
+	if err := json.Unmarshal(bs, &p); err != nil {
+		t.Fatal(err)
+	}
+	if e := p.ToError(); e.Code != ErrValidationError.Code {
+		t.Errorf("code: got %d, want %d", e.Code, ErrValidationError.Code)
+	}
+}
@@ -0,0 +1,111 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ModuleName 本包内置错误码所属的模块名
+const ModuleName = "github.com/runner-mei/errors"
+
+// CodeInfo 一个已注册的错误码的描述
+type CodeInfo struct {
+	Code       int    `json:"code"`
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	HTTPStatus int    `json:"http_status"`
+	Module     string `json:"module,omitempty"`
+}
+
+// New 用默认消息创建一个新的 *Error
+func (info CodeInfo) New() *Error {
+	return &Error{Code: info.Code, Message: info.Message}
+}
+
+type codeRegistry struct {
+	mu     sync.RWMutex
+	byCode map[int]CodeInfo
+	byName map[string]CodeInfo
+}
+
+var registry = &codeRegistry{
+	byCode: map[int]CodeInfo{},
+	byName: map[string]CodeInfo{},
+}
+
+// Register 注册一个错误码，code 或 name 已被注册时返回错误。
+//
+// HTTPStatus 为 0 时按 ToHttpCode(code) 计算，不为 0 时必须与之一致。
+func Register(info CodeInfo) error {
+	if info.Code <= 0 {
+		return fmt.Errorf("register error code %q: code must be positive", info.Name)
+	}
+	if info.Name == "" {
+		return fmt.Errorf("register error code %d: name is empty", info.Code)
+	}
+	if info.HTTPStatus == 0 {
+		info.HTTPStatus = ToHttpCode(info.Code)
+	} else if info.HTTPStatus != ToHttpCode(info.Code) {
+		return fmt.Errorf("register error code %d(%s): http status %d does not match code, want %d",
+			info.Code, info.Name, info.HTTPStatus, ToHttpCode(info.Code))
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if old, ok := registry.byCode[info.Code]; ok {
+		return fmt.Errorf("register error code %d(%s) of module %q: code is already registered as %s by module %q",
+			info.Code, info.Name, info.Module, old.Name, old.Module)
+	}
+	if old, ok := registry.byName[info.Name]; ok {
+		return fmt.Errorf("register error code %d(%s) of module %q: name is already registered with code %d by module %q",
+			info.Code, info.Name, info.Module, old.Code, old.Module)
+	}
+	registry.byCode[info.Code] = info
+	registry.byName[info.Name] = info
+	return nil
+}
+
+// MustRegister 注册一个错误码并返回它对应的 *Error，注册失败时 panic，一般在包初始化时调用
+func MustRegister(info CodeInfo) *Error {
+	if err := Register(info); err != nil {
+		panic(err)
+	}
+	return info.New()
+}
+
+// LookupCode 按错误码查找注册信息
+func LookupCode(code int) (CodeInfo, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	info, ok := registry.byCode[code]
+	return info, ok
+}
+
+// LookupName 按符号名查找注册信息
+func LookupName(name string) (CodeInfo, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	info, ok := registry.byName[name]
+	return info, ok
+}
+
+// RegisteredCodes 返回所有已注册的错误码，按 code 排序
+func RegisteredCodes() []CodeInfo {
+	registry.mu.RLock()
+	list := make([]CodeInfo, 0, len(registry.byCode))
+	for _, info := range registry.byCode {
+		list = append(list, info)
+	}
+	registry.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+func define(code int, name, msg string) *Error {
+	return MustRegister(CodeInfo{Code: code, Name: name, Message: msg, Module: ModuleName})
+}
@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	info := CodeInfo{Code: 418901, Name: "ErrTestTeapot", Message: "teapot", Module: "test"}
+	if err := Register(info); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Register(CodeInfo{Code: 418901, Name: "ErrTestOther", Message: "other", Module: "other"})
+	if err == nil || !strings.Contains(err.Error(), "code is already registered as ErrTestTeapot") {
+		t.Errorf("duplicate code: got %v", err)
+	}
+	err = Register(CodeInfo{Code: 418902, Name: "ErrTestTeapot", Message: "other", Module: "other"})
+	if err == nil || !strings.Contains(err.Error(), "name is already registered with code 418901") {
+		t.Errorf("duplicate name: got %v", err)
+	}
+	if _, ok := LookupCode(418902); ok {
+		t.Error("a rejected code should not be registered")
+	}
+
+	// 与内置的错误码冲突
+	if err := Register(CodeInfo{Code: ErrNotFound.Code, Name: "ErrTestNotFound"}); err == nil {
+		t.Error("a builtin code should be rejected")
+	}
+	if err := Register(CodeInfo{Code: 404999, Name: "ErrNotFound"}); err == nil {
+		t.Error("a builtin name should be rejected")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustRegister should panic on a duplicate")
+		}
+	}()
+	MustRegister(info)
+}
+
+func TestRegisterValidates(t *testing.T) {
+	tests := []CodeInfo{
+		{Code: 0, Name: "ErrTestZero"},
+		{Code: 418903, Name: ""},
+		{Code: 418904, Name: "ErrTestStatus", HTTPStatus: 500},
+	}
+	for _, info := range tests {
+		if err := Register(info); err == nil {
+			t.Errorf("%#v: should be rejected", info)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	info, ok := LookupCode(ErrRecordNotFound.Code)
+	if !ok || info.Name != "ErrRecordNotFound" || info.Message != ErrRecordNotFound.Message ||
+		info.HTTPStatus != 404 || info.Module != ModuleName {
+		t.Errorf("LookupCode: got %#v %v", info, ok)
+	}
+	info, ok = LookupName("ErrConflict")
+	if !ok || info.Code != ErrConflict.Code {
+		t.Errorf("LookupName: got %#v %v", info, ok)
+	}
+	if _, ok := LookupName("ErrNoSuchName"); ok {
+		t.Error("LookupName: unknown name should not be found")
+	}
+	if e := info.New(); e.Code != ErrConflict.Code || e.Message != ErrConflict.Message {
+		t.Errorf("New: got %#v", e)
+	}
+
+	list := RegisteredCodes()
+	for idx := 1; idx < len(list); idx++ {
+		if list[idx-1].Code >= list[idx].Code {
+			t.Fatalf("RegisteredCodes is not sorted at %d", idx)
+		}
+	}
+	var found bool
+	for _, info := range list {
+		if info.Code == ErrTimeout.Code {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("RegisteredCodes should include the builtin codes")
+	}
+}
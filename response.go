@@ -0,0 +1,394 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ResponseDecoder 将一个错误响应的 body 解码成 *Error
+type ResponseDecoder func(response *http.Response, body []byte) (*Error, error)
+
+var (
+	// MaxErrorBodySize ToResponseError 最多读取的 body 大小
+	MaxErrorBodySize int64 = 1 << 20
+
+	// ErrorBodySnippetSize 解码失败时在 Details 中保留的原始 body 的最大字节数
+	ErrorBodySnippetSize = 512
+)
+
+var responseDecoders = struct {
+	sync.RWMutex
+	byType map[string]ResponseDecoder
+}{
+	byType: map[string]ResponseDecoder{},
+}
+
+func init() {
+	RegisterResponseDecoder("application/json", decodeJSONResponse)
+	RegisterResponseDecoder(ProblemContentType, decodeProblemResponse)
+	RegisterResponseDecoder("application/xml", decodeXMLResponse)
+	RegisterResponseDecoder("text/xml", decodeXMLResponse)
+	RegisterResponseDecoder("text/html", decodeHTMLResponse)
+	RegisterResponseDecoder("application/xhtml+xml", decodeHTMLResponse)
+	RegisterResponseDecoder("text/plain", decodeTextResponse)
+}
+
+// RegisterResponseDecoder 为 mediaType (如 "application/json") 注册一个 ResponseDecoder,
+// 已存在时会覆盖它, decoder 为 nil 时删除它
+func RegisterResponseDecoder(mediaType string, decoder ResponseDecoder) {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	responseDecoders.Lock()
+	defer responseDecoders.Unlock()
+	if decoder == nil {
+		delete(responseDecoders.byType, mediaType)
+		return
+	}
+	responseDecoders.byType[mediaType] = decoder
+}
+
+func lookupResponseDecoder(mediaType string, body []byte) ResponseDecoder {
+	responseDecoders.RLock()
+	defer responseDecoders.RUnlock()
+
+	if decoder := responseDecoders.byType[mediaType]; decoder != nil {
+		return decoder
+	}
+
+	// 结构化后缀, 如 application/vnd.api+json
+	if pos := strings.LastIndexByte(mediaType, '+'); pos >= 0 {
+		switch mediaType[pos+1:] {
+		case "json":
+			return responseDecoders.byType["application/json"]
+		case "xml":
+			return responseDecoders.byType["application/xml"]
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		return responseDecoders.byType["text/plain"]
+	}
+
+	// 没有或未知的 Content-Type 时按内容猜测
+	switch trimmed := bytes.TrimSpace(body); {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return responseDecoders.byType["application/json"]
+	case len(trimmed) > 0 && trimmed[0] == '<':
+		head := trimmed
+		if len(head) > 512 {
+			head = head[:512]
+		}
+		if bytes.Contains(bytes.ToLower(head), []byte("<html")) {
+			return responseDecoders.byType["text/html"]
+		}
+		return responseDecoders.byType["application/xml"]
+	}
+	return responseDecoders.byType["text/plain"]
+}
+
+// ToResponseError 将一个错误的 HTTP 响应转换成 error
+//
+// 它按 Content-Type 选择已注册的 ResponseDecoder, 解码失败时返回的错误中仍保留
+// response.StatusCode, 并在 Details 中带上原始 body 的一个片段。响应中有 Retry-After 头时,
+// 它会被放在返回的错误的 Retry 中。
+func ToResponseError(response *http.Response) error {
+	e := toResponseError(response)
+	if e.Retry == nil {
+		e.Retry = parseRetryAfter(response.Header.Get("Retry-After"))
+	}
+	return e
+}
+
+func toResponseError(response *http.Response) *Error {
+	if response.Body == nil {
+		return &Error{Code: responseErrorCode(response, http.StatusNoContent), Message: "no content"}
+	}
+	defer io.Copy(io.Discard, response.Body)
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, MaxErrorBodySize))
+	if err != nil {
+		return &Error{
+			Code:    response.StatusCode,
+			Message: "read error info: " + err.Error(),
+			Details: bodySnippet(body),
+			Cause:   err,
+		}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return &Error{Code: responseErrorCode(response, response.StatusCode), Message: response.Status}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	decoder := lookupResponseDecoder(strings.ToLower(mediaType), body)
+	if decoder == nil {
+		decoder = decodeTextResponse
+	}
+
+	e, err := decoder(response, body)
+	if err != nil {
+		return &Error{
+			Code:    response.StatusCode,
+			Message: "read error info: " + err.Error(),
+			Details: bodySnippet(body),
+			Cause:   err,
+		}
+	}
+	if e.Code == 0 || e.Code == response.StatusCode {
+		e.Code = responseErrorCode(response, response.StatusCode)
+	}
+	return e
+}
+
+// responseErrorCode 返回 ErrorCodeHeader 头中的错误码, 没有或它与状态码不一致时返回 defaultCode
+func responseErrorCode(response *http.Response, defaultCode int) int {
+	s := response.Header.Get(ErrorCodeHeader)
+	if s == "" {
+		return defaultCode
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code <= 0 {
+		return defaultCode
+	}
+	// 多个错误的状态可能由 MultiStatusPolicy 归纳得到, 与错误码不一致
+	if response.StatusCode != 0 && ToHttpCode(code) != response.StatusCode &&
+		ToHttpCode(code) != ToHttpCode(ErrMultipleError.Code) {
+		return defaultCode
+	}
+	return code
+}
+
+func bodySnippet(body []byte) string {
+	if len(body) <= ErrorBodySnippetSize {
+		return string(body)
+	}
+	body = body[:ErrorBodySnippetSize]
+	for len(body) > 0 && !utf8.Valid(body) {
+		body = body[:len(body)-1]
+	}
+	return string(body) + "..."
+}
+
+func decodeTextResponse(response *http.Response, body []byte) (*Error, error) {
+	return &Error{Code: response.StatusCode, Message: string(body)}, nil
+}
+
+func decodeProblemResponse(response *http.Response, body []byte) (*Error, error) {
+	var problem Problem
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return nil, err
+	}
+	if problem.Status == 0 {
+		problem.Status = response.StatusCode
+	}
+	return problem.ToError(), nil
+}
+
+func decodeJSONResponse(response *http.Response, body []byte) (*Error, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return errorFromValues(response.StatusCode, response.Status, v), nil
+	case []interface{}:
+		e := &Error{Code: response.StatusCode, Message: response.Status}
+		for _, item := range v {
+			if values, ok := item.(map[string]interface{}); ok {
+				e.Internals = append(e.Internals, *errorFromValues(response.StatusCode, response.Status, values))
+			} else {
+				e.Internals = append(e.Internals, Error{Code: response.StatusCode, Message: fmt.Sprint(item)})
+			}
+		}
+		if len(e.Internals) == 1 {
+			return &e.Internals[0], nil
+		}
+		return e, nil
+	case string:
+		return &Error{Code: response.StatusCode, Message: v}, nil
+	case nil:
+		return &Error{Code: response.StatusCode, Message: response.Status}, nil
+	default:
+		return &Error{Code: response.StatusCode, Message: fmt.Sprint(v)}, nil
+	}
+}
+
+// errorFromValues 用 JSON 或 XML 中的值创建错误, 没有 message, error, msg 或 title 时用 status 作为消息
+func errorFromValues(statusCode int, status string, values map[string]interface{}) *Error {
+	var msg, msgKey string
+	for _, key := range []string{"message", "error", "msg", "title"} {
+		o := values[key]
+		if o == nil {
+			continue
+		}
+		msg, _ = o.(string)
+		if msg != "" {
+			msgKey = key
+			break
+		}
+	}
+	if msg == "" {
+		msg = status
+		if msg == "" {
+			msg = http.StatusText(statusCode)
+		}
+	}
+
+	e := &Error{
+		Code:    statusCode,
+		Message: msg,
+	}
+	if details, ok := values["details"].(string); ok {
+		e.Details = details
+	}
+	for key, value := range values {
+		switch key {
+		case "code", "details", msgKey:
+			continue
+		case "data":
+			if data, ok := value.(map[string]interface{}); ok {
+				for k, v := range data {
+					e.setDataValue(k, v)
+				}
+				continue
+			}
+		case "attrs":
+			if attrs, ok := value.(map[string]interface{}); ok {
+				for k, v := range attrs {
+					if e.Values == nil {
+						e.Values = Attrs{}
+					}
+					e.Values.Set(k, v)
+				}
+				continue
+			}
+		case "retry":
+			var hint RetryHint
+			if problemExtension(value, &hint) {
+				e.Retry = &hint
+				continue
+			}
+		case "internals":
+			if list, ok := value.([]interface{}); ok {
+				for _, item := range list {
+					if m, ok := item.(map[string]interface{}); ok {
+						e.Internals = append(e.Internals, *errorFromValues(statusCode, status, m))
+					} else {
+						e.Internals = append(e.Internals, Error{Code: statusCode, Message: fmt.Sprint(item)})
+					}
+				}
+				continue
+			}
+		}
+		e.setDataValue(key, value)
+	}
+
+	if v := values["code"]; v != nil {
+		switch value := v.(type) {
+		case json.Number:
+			i, err := strconv.Atoi(value.String())
+			if err == nil {
+				e.Code = i
+			}
+		case string:
+			i, err := strconv.Atoi(value)
+			if err == nil {
+				e.Code = i
+			}
+		case int32:
+			e.Code = int(value)
+		case int64:
+			e.Code = int(value)
+		case int:
+			e.Code = value
+		case uint32:
+			e.Code = int(value)
+		case uint64:
+			e.Code = int(value)
+		case uint:
+			e.Code = int(value)
+		}
+	}
+	return e
+}
+
+// decodeXMLResponse 将根元素的直接子元素当作键值对, 如
+//
+//	<error><code>404</code><message>not found</message></error>
+func decodeXMLResponse(response *http.Response, body []byte) (*Error, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	values := map[string]interface{}{}
+
+	depth := 0
+	var name string
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				name = t.Name.Local
+				text.Reset()
+			}
+		case xml.CharData:
+			if depth == 2 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				if _, exists := values[name]; !exists {
+					values[name] = strings.TrimSpace(text.String())
+				}
+			}
+			depth--
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no error info in xml")
+	}
+	return errorFromValues(response.StatusCode, response.Status, values), nil
+}
+
+var (
+	htmlTitleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlDropRe  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlTagRe   = regexp.MustCompile(`(?s)<[^>]*>`)
+	spacesRe    = regexp.MustCompile(`\s+`)
+)
+
+// decodeHTMLResponse 处理代理服务器等返回的 HTML 错误页面, 用 title 作为消息, 正文作为 Details
+func decodeHTMLResponse(response *http.Response, body []byte) (*Error, error) {
+	e := &Error{Code: response.StatusCode, Message: response.Status}
+	if m := htmlTitleRe.FindSubmatch(body); m != nil {
+		if title := strings.TrimSpace(html.UnescapeString(string(m[1]))); title != "" {
+			e.Message = title
+		}
+	}
+
+	text := htmlDropRe.ReplaceAll(body, nil)
+	text = htmlTagRe.ReplaceAll(text, []byte(" "))
+	e.Details = bodySnippet([]byte(strings.TrimSpace(spacesRe.ReplaceAllString(html.UnescapeString(string(text)), " "))))
+	return e, nil
+}
@@ -0,0 +1,129 @@
+package errors
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func newResponse(status int, contentType, body string) *http.Response {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestResponseErrorWithoutMessage(t *testing.T) {
+	tests := []struct {
+		body  string
+		value string
+	}{
+		{`{}`, ""},
+		{`{"status":"fail"}`, "fail"},
+	}
+	for _, test := range tests {
+		e := ToError(ToResponseError(newResponse(http.StatusBadGateway, "application/json", test.body)))
+		if e.Message != http.StatusText(http.StatusBadGateway) {
+			t.Errorf("%s: message got %q", test.body, e.Message)
+		}
+		if e.Code != http.StatusBadGateway {
+			t.Errorf("%s: code got %d", test.body, e.Code)
+		}
+		if test.value != "" {
+			if v, _ := e.Value("status"); v != test.value {
+				t.Errorf("%s: status got %#v", test.body, v)
+			}
+		}
+	}
+}
+
+func TestResponseErrorXML(t *testing.T) {
+	body := `<?xml version="1.0"?><error><code>404001</code><message>no such bucket</message><resource>b1</resource></error>`
+	for _, contentType := range []string{"application/xml", "text/xml", ""} {
+		e := ToError(ToResponseError(newResponse(http.StatusNotFound, contentType, body)))
+		if e.Code != 404001 || e.Message != "no such bucket" {
+			t.Errorf("%q: got %d %q", contentType, e.Code, e.Message)
+		}
+		if v, _ := e.Value("resource"); v != "b1" {
+			t.Errorf("%q: resource got %#v", contentType, v)
+		}
+	}
+}
+
+func TestResponseErrorHTML(t *testing.T) {
+	body := `<html><head><title>502 Bad Gateway</title><style>body{}</style></head>
+<body><center><h1>502 Bad Gateway</h1></center><hr><center>nginx</center></body></html>`
+	for _, contentType := range []string{"text/html; charset=utf-8", ""} {
+		e := ToError(ToResponseError(newResponse(http.StatusBadGateway, contentType, body)))
+		if e.Code != http.StatusBadGateway || e.Message != "502 Bad Gateway" {
+			t.Errorf("%q: got %d %q", contentType, e.Code, e.Message)
+		}
+		if e.Details != "502 Bad Gateway nginx" {
+			t.Errorf("%q: details got %q", contentType, e.Details)
+		}
+	}
+}
+
+func TestResponseErrorJSONArray(t *testing.T) {
+	body := `[{"code":400001,"message":"a is required"},{"message":"b is invalid"},"c"]`
+	e := ToError(ToResponseError(newResponse(http.StatusBadRequest, "application/json", body)))
+	if e.Code != http.StatusBadRequest || e.Message != http.StatusText(http.StatusBadRequest) {
+		t.Errorf("got %d %q", e.Code, e.Message)
+	}
+	if len(e.Internals) != 3 {
+		t.Fatalf("internals got %#v", e.Internals)
+	}
+	if e.Internals[0].Code != 400001 || e.Internals[0].Message != "a is required" {
+		t.Errorf("internals[0] got %#v", e.Internals[0])
+	}
+	if e.Internals[1].Code != http.StatusBadRequest || e.Internals[1].Message != "b is invalid" {
+		t.Errorf("internals[1] got %#v", e.Internals[1])
+	}
+	if e.Internals[2].Message != "c" {
+		t.Errorf("internals[2] got %#v", e.Internals[2])
+	}
+
+	// 只有一个元素时直接返回它
+	e = ToError(ToResponseError(newResponse(http.StatusBadRequest, "application/json", `[{"message":"only"}]`)))
+	if e.Message != "only" || len(e.Internals) != 0 {
+		t.Errorf("single element got %#v", e)
+	}
+}
+
+func TestResponseErrorEmptyBody(t *testing.T) {
+	e := ToError(ToResponseError(newResponse(http.StatusServiceUnavailable, "application/json", "  \n")))
+	if e.Code != http.StatusServiceUnavailable || e.Message != http.StatusText(http.StatusServiceUnavailable) {
+		t.Errorf("got %d %q", e.Code, e.Message)
+	}
+}
+
+func TestResponseErrorBadBodySnippet(t *testing.T) {
+	body := `{"message": "` + strings.Repeat("好", ErrorBodySnippetSize)
+	e := ToError(ToResponseError(newResponse(http.StatusInternalServerError, "application/json", body)))
+	if e.Code != http.StatusInternalServerError {
+		t.Errorf("code got %d", e.Code)
+	}
+	if !strings.HasPrefix(e.Message, "read error info: ") || e.Cause == nil {
+		t.Errorf("message got %q, cause %v", e.Message, e.Cause)
+	}
+	if !strings.HasSuffix(e.Details, "...") || len(e.Details) > ErrorBodySnippetSize+len("...") {
+		t.Errorf("details got %d bytes", len(e.Details))
+	}
+	if !strings.HasPrefix(e.Details, `{"message": "好`) || !utf8.ValidString(e.Details) {
+		t.Errorf("details got %q", e.Details)
+	}
+
+	// 短的 body 原样保留
+	e = ToError(ToResponseError(newResponse(http.StatusInternalServerError, "application/json", `{"message":`)))
+	if e.Details != `{"message":` {
+		t.Errorf("details got %q", e.Details)
+	}
+}
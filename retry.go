@@ -0,0 +1,343 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy 决定 Retry 的重试次数和重试间隔
+//
+// 第 n 次重试前等待 InitialDelay * Multiplier^(n-1), 但不超过 MaxDelay, 然后按 Jitter 随机地减少一部分。
+// 错误链中有 RetryAfter() time.Duration 返回大于 0 的值时, 至少等待这么久。
+type RetryPolicy struct {
+	// MaxAttempts 最多执行的次数 (包括第一次), 小于等于 0 时为 3
+	MaxAttempts int
+	// InitialDelay 第一次重试前的等待时间, 小于等于 0 时为 100ms
+	InitialDelay time.Duration
+	// MaxDelay 等待时间的上限, 小于等于 0 时为 10s
+	MaxDelay time.Duration
+	// Multiplier 每次重试后等待时间的倍数, 小于 1 时为 2
+	Multiplier float64
+	// Jitter 等待时间中随机减少的比例, 取值为 0 到 1, 0 表示不随机
+	Jitter float64
+	// ShouldRetry 判断一个错误是否需要重试, 为 nil 时使用 ShouldRetry
+	ShouldRetry func(err error) bool
+}
+
+// DefaultRetryPolicy 缺省的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  3,
+	InitialDelay: 100 * time.Millisecond,
+	MaxDelay:     10 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// Delay 返回第 attempt 次重试 (从 1 开始) 前的等待时间, 不包括 RetryAfter 的提示
+func (policy *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := policy.InitialDelay
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+	maxDelay := policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	d := float64(delay)
+	for i := 1; i < attempt && d < float64(maxDelay); i++ {
+		d *= multiplier
+	}
+	if d > float64(maxDelay) {
+		d = float64(maxDelay)
+	}
+	if policy.Jitter > 0 {
+		jitter := policy.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		d -= d * jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+func (policy *RetryPolicy) maxAttempts() int {
+	if policy.MaxAttempts <= 0 {
+		return 3
+	}
+	return policy.MaxAttempts
+}
+
+func (policy *RetryPolicy) shouldRetry(err error) bool {
+	if policy.ShouldRetry != nil {
+		return policy.ShouldRetry(err)
+	}
+	return ShouldRetry(err)
+}
+
+// Retry 执行 fn, 失败时按 policy 重试, 直到成功、错误不需要重试、次数用完或 ctx 结束。
+//
+// 只执行了一次时返回 fn 的错误本身 (即使 ctx 随后结束了), 否则返回一个包含每一次错误的
+// ErrMultipleError 的 *Error, 它的消息为 "failed after N attempts", N 是 fn 执行的次数,
+// 因 ctx 结束而停止时 ctx.Err() 也会放在其中。
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	var errs []error
+	var attempts int
+	maxAttempts := policy.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		attempts = attempt
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+
+		if attempt >= maxAttempts || !policy.shouldRetry(err) {
+			break
+		}
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		delay := policy.Delay(attempt)
+		if after := RetryAfter(err); after > delay {
+			delay = after
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			errs = append(errs, ctx.Err())
+		case <-timer.C:
+			continue
+		}
+		break
+	}
+	if attempts == 1 {
+		return errs[0]
+	}
+	return newMultiError(fmt.Sprintf("failed after %d attempts", attempts), errs)
+}
+
+// RetryAfter 返回错误链中第一个 RetryAfter() time.Duration 返回的大于 0 的值, 没有时返回 0
+func RetryAfter(err error) time.Duration {
+	var after time.Duration
+	Walk(err, func(e error) bool {
+		if r, ok := e.(interface{ RetryAfter() time.Duration }); ok && r.RetryAfter() > 0 {
+			after = r.RetryAfter()
+			return true
+		}
+		return false
+	})
+	return after
+}
+
+// ShouldRetry 按错误的分类判断它是否值得重试, 依次检查:
+//
+//	错误链中第一个显式的重试标记 (*Error 的 Retry, Retryable() bool 或可以重试的数据库错误)
+//	多个错误 (包括 errors.Join 的结果) 只有在它的每一个成员都值得重试时才重试
+//	context.Canceled, TLS 错误和域名不存在 (*net.DNSError 的 IsNotFound) 不重试
+//	超时、网络错误和 ErrPending 重试
+//	HTTP 状态为 429, 500, 502, 503 或 504 时重试, 其它的 (包括本包自定义的 56x 到 59x) 不重试
+func ShouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if retryable, ok := findRetryMarker(err); ok {
+		return retryable
+	}
+
+	if members, ok := findMembers(err); ok {
+		for _, member := range members {
+			if !ShouldRetry(member) {
+				return false
+			}
+		}
+		return len(members) > 0
+	}
+
+	if Is(err, context.Canceled) {
+		return false
+	}
+	if ec, ok := GetErrorCode(err); ok && ec == ErrTLSError.Code {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	if IsTimeoutError(err) || IsNetworkError(err) || IsPendingError(err) {
+		return true
+	}
+
+	hc, ok := GetHttpCode(err)
+	if !ok {
+		return false
+	}
+	switch hc {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// findMembers 返回错误链中第一个多个错误 (ErrMultipleError 的 *Error 或 errors.Join 的结果等) 的成员
+func findMembers(err error) (members []error, found bool) {
+	Walk(err, func(e error) bool {
+		if x, ok := e.(*Error); ok && x.isMultiple() {
+			members, found = x.Errors(), true
+		} else if x, ok := e.(interface{ Unwrap() []error }); ok {
+			members, found = x.Unwrap(), true
+		}
+		return found
+	})
+	return members, found
+}
+
+// RetryHint 是 *Error 上的重试提示, 如 "这个 503 错误可以在 30 秒后重试"
+//
+// 在 JSON 中它是 "retry" 成员, After 以秒为单位:
+//
+//	"retry": {"retryable": true, "after": 30}
+type RetryHint struct {
+	Retryable bool
+	After     time.Duration
+}
+
+type retryHintJSON struct {
+	Retryable bool    `json:"retryable"`
+	After     float64 `json:"after,omitempty"`
+}
+
+func (hint RetryHint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&retryHintJSON{
+		Retryable: hint.Retryable,
+		After:     hint.After.Seconds(),
+	})
+}
+
+func (hint *RetryHint) UnmarshalJSON(bs []byte) error {
+	var value retryHintJSON
+	if err := json.Unmarshal(bs, &value); err != nil {
+		return err
+	}
+	hint.Retryable = value.Retryable
+	hint.After = time.Duration(value.After * float64(time.Second))
+	return nil
+}
+
+// WithRetry 标记这个错误可以重试, after 大于 0 时表示至少要等待这么久才能重试
+func (err *Error) WithRetry(after time.Duration) *Error {
+	err.Retry = &RetryHint{Retryable: true, After: after}
+	return err
+}
+
+// WithoutRetry 标记这个错误不能重试
+func (err *Error) WithoutRetry() *Error {
+	err.Retry = &RetryHint{Retryable: false}
+	return err
+}
+
+// RetryAfter 返回重试提示中的等待时间, 没有时返回 0
+func (err *Error) RetryAfter() time.Duration {
+	if err.Retry == nil || !err.Retry.Retryable {
+		return 0
+	}
+	return err.Retry.After
+}
+
+// retryMarker 返回 err 自身显式带有的重试标记, 即 *Error 的 Retry, Retryable() bool 的结果,
+// 或 ClassifySQLError 认为可以重试的数据库错误 (如死锁)
+func retryMarker(err error) (retryable bool, ok bool) {
+	if e, isError := err.(*Error); isError {
+		if e.Retry == nil {
+			return false, false
+		}
+		return e.Retry.Retryable, true
+	}
+	if r, isRetryable := err.(interface{ Retryable() bool }); isRetryable {
+		return r.Retryable(), true
+	}
+	if kind, isSQL := classifySQL(err); isSQL && kind.retryable {
+		return true, true
+	}
+	return false, false
+}
+
+// IsRetryable 判断 err 是否可以重试
+//
+// 它先找错误链中第一个显式的重试标记 (*Error 的 Retry, Retryable() bool 或可以重试的数据库错误),
+// 没有时再看错误链中是否有 Temporary() bool 或 Timeout() bool 返回 true。
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if retryable, ok := findRetryMarker(err); ok {
+		return retryable
+	}
+	return Walk(err, func(e error) bool {
+		if t, ok := e.(interface{ Temporary() bool }); ok && t.Temporary() {
+			return true
+		}
+		t, ok := e.(interface{ Timeout() bool })
+		return ok && t.Timeout()
+	})
+}
+
+func findRetryMarker(err error) (retryable bool, found bool) {
+	Walk(err, func(e error) bool {
+		retryable, found = retryMarker(e)
+		return found
+	})
+	return retryable, found
+}
+
+// parseRetryAfter 解析 Retry-After 头, 它可以是秒数或 HTTP 日期, 无法解析时返回 nil
+func parseRetryAfter(s string) *RetryHint {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		if seconds < 0 {
+			return nil
+		}
+		return &RetryHint{Retryable: true, After: time.Duration(seconds) * time.Second}
+	}
+	t, err := http.ParseTime(s)
+	if err != nil {
+		return nil
+	}
+	after := time.Until(t)
+	if after < 0 {
+		after = 0
+	}
+	return &RetryHint{Retryable: true, After: after}
+}
+
+// formatRetryAfter 将等待时间转换成 Retry-After 头中的秒数, 不足一秒的部分向上取整
+func formatRetryAfter(after time.Duration) string {
+	seconds := int64(after / time.Second)
+	if after%time.Second != 0 {
+		seconds++
+	}
+	return strconv.FormatInt(seconds, 10)
+}
@@ -0,0 +1,189 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAttempts(t *testing.T) {
+	var calls int
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond}, func(context.Context) error {
+		calls++
+		return ErrTimeout
+	})
+	if calls != 3 {
+		t.Errorf("calls: got %d, want 3", calls)
+	}
+	e := ToError(err)
+	if !e.isMultiple() || len(e.Errors()) != 3 {
+		t.Fatalf("got %#v", err)
+	}
+	if e.Message != "failed after 3 attempts" {
+		t.Errorf("message: got %q", e.Message)
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int
+	policy := RetryPolicy{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: time.Hour, Multiplier: 3600000}
+	err := Retry(ctx, policy, func(context.Context) error {
+		calls++
+		if calls == 2 {
+			time.AfterFunc(10*time.Millisecond, cancel)
+		}
+		return ErrTimeout
+	})
+	if calls != 2 {
+		t.Errorf("calls: got %d, want 2", calls)
+	}
+	e := ToError(err)
+	if e.Message != "failed after 2 attempts" {
+		t.Errorf("message: got %q", e.Message)
+	}
+	if members := e.Errors(); len(members) != 3 || members[2] != context.Canceled {
+		t.Errorf("errors: got %v", members)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	var calls int
+	err := Retry(context.Background(), DefaultRetryPolicy, func(context.Context) error {
+		calls++
+		return ErrNotFound
+	})
+	if calls != 1 || err != ErrNotFound {
+		t.Errorf("got %d calls, %#v", calls, err)
+	}
+}
+
+func TestRetryCanceledAfterFirstAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Retry(ctx, DefaultRetryPolicy, func(context.Context) error {
+		cancel()
+		return ErrTimeout
+	})
+	if err != ErrTimeout {
+		t.Errorf("got %#v, want fn's error", err)
+	}
+}
+
+func TestShouldRetryDNS(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}
+	if ShouldRetry(notFound) || ShouldRetry(fmt.Errorf("dial: %w", notFound)) {
+		t.Error("a missing host should not be retried")
+	}
+	temporary := &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}
+	if !ShouldRetry(temporary) {
+		t.Error("a temporary DNS failure should be retried")
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		after  time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), time.Hour, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, test := range tests {
+		response := newResponse(http.StatusServiceUnavailable, "text/plain", "busy")
+		if test.header != "" {
+			response.Header.Set("Retry-After", test.header)
+		}
+		e := ToError(ToResponseError(response))
+		if (e.Retry != nil) != test.ok {
+			t.Errorf("%q: retry got %#v", test.header, e.Retry)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+		if !e.Retry.Retryable {
+			t.Errorf("%q: should be retryable", test.header)
+		}
+		// HTTP 日期只精确到秒
+		if d := e.Retry.After - test.after; d < -2*time.Second || d > 0 {
+			t.Errorf("%q: after got %v, want %v", test.header, e.Retry.After, test.after)
+		}
+	}
+
+	// body 中的 retry 优先于 Retry-After 头
+	response := newResponse(http.StatusServiceUnavailable, "application/json",
+		`{"message":"busy","retry":{"retryable":true,"after":5}}`)
+	response.Header.Set("Retry-After", "30")
+	if after := RetryAfter(ToResponseError(response)); after != 5*time.Second {
+		t.Errorf("body: after got %v", after)
+	}
+}
+
+func TestWriteErrorRetryAfter(t *testing.T) {
+	tests := []struct {
+		err    error
+		header string
+	}{
+		{NewError(http.StatusServiceUnavailable, "busy").WithRetry(30 * time.Second), "30"},
+		{NewError(http.StatusTooManyRequests, "slow down").WithRetry(1500 * time.Millisecond), "2"},
+		{Wrap(NewError(http.StatusServiceUnavailable, "busy").WithRetry(time.Minute), "call"), "60"},
+		{NewError(http.StatusServiceUnavailable, "busy").WithRetry(0), ""},
+		{NewError(http.StatusServiceUnavailable, "busy").WithoutRetry(), ""},
+		{ErrTimeout, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		WriteError(w, httptest.NewRequest(http.MethodGet, "/", nil), test.err)
+		if got := w.Header().Get("Retry-After"); got != test.header {
+			t.Errorf("%v: Retry-After got %q, want %q", test.err, got, test.header)
+		}
+	}
+
+	// 经过 WriteError 和 ToResponseError 后仍然保留
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodGet, "/", nil), tests[0].err)
+	if after := RetryAfter(ToResponseError(w.Result())); after != 30*time.Second {
+		t.Errorf("round trip: after got %v", after)
+	}
+}
+
+func TestRetryHintJSON(t *testing.T) {
+	e := &Error{Code: http.StatusServiceUnavailable * 1000, Message: "busy"}
+	e.WithRetry(30 * time.Second)
+	bs, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`{"code":%d,"message":"busy","retry":{"retryable":true,"after":30}}`, http.StatusServiceUnavailable*1000)
+	if string(bs) != want {
+		t.Errorf("got  %s\nwant %s", bs, want)
+	}
+
+	bs, _ = json.Marshal(&Error{Code: 400, Message: "bad", Retry: &RetryHint{}})
+	if string(bs) != `{"code":400,"message":"bad","retry":{"retryable":false}}` {
+		t.Errorf("without after: got %s", bs)
+	}
+	bs, _ = json.Marshal(&Error{Code: 400, Message: "bad"})
+	if string(bs) != `{"code":400,"message":"bad"}` {
+		t.Errorf("without retry: got %s", bs)
+	}
+
+	var decoded Error
+	if err := json.Unmarshal([]byte(`{"code":503,"message":"busy","retry":{"retryable":true,"after":1.5}}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Retry == nil || !decoded.Retry.Retryable || decoded.Retry.After != 1500*time.Millisecond {
+		t.Errorf("decoded: got %#v", decoded.Retry)
+	}
+}
@@ -0,0 +1,213 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// RPCCode 是 gRPC 规范中的 17 个标准状态码, 取值与 google.golang.org/grpc/codes 相同,
+// 这样 grpc 的适配层可以直接用 codes.Code(c) 转换, 本包不需要依赖 grpc
+type RPCCode uint32
+
+const (
+	RPCOK                 RPCCode = 0
+	RPCCanceled           RPCCode = 1
+	RPCUnknown            RPCCode = 2
+	RPCInvalidArgument    RPCCode = 3
+	RPCDeadlineExceeded   RPCCode = 4
+	RPCNotFound           RPCCode = 5
+	RPCAlreadyExists      RPCCode = 6
+	RPCPermissionDenied   RPCCode = 7
+	RPCResourceExhausted  RPCCode = 8
+	RPCFailedPrecondition RPCCode = 9
+	RPCAborted            RPCCode = 10
+	RPCOutOfRange         RPCCode = 11
+	RPCUnimplemented      RPCCode = 12
+	RPCInternal           RPCCode = 13
+	RPCUnavailable        RPCCode = 14
+	RPCDataLoss           RPCCode = 15
+	RPCUnauthenticated    RPCCode = 16
+)
+
+var rpcCodeNames = [...]string{
+	RPCOK:                 "OK",
+	RPCCanceled:           "CANCELLED",
+	RPCUnknown:            "UNKNOWN",
+	RPCInvalidArgument:    "INVALID_ARGUMENT",
+	RPCDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	RPCNotFound:           "NOT_FOUND",
+	RPCAlreadyExists:      "ALREADY_EXISTS",
+	RPCPermissionDenied:   "PERMISSION_DENIED",
+	RPCResourceExhausted:  "RESOURCE_EXHAUSTED",
+	RPCFailedPrecondition: "FAILED_PRECONDITION",
+	RPCAborted:            "ABORTED",
+	RPCOutOfRange:         "OUT_OF_RANGE",
+	RPCUnimplemented:      "UNIMPLEMENTED",
+	RPCInternal:           "INTERNAL",
+	RPCUnavailable:        "UNAVAILABLE",
+	RPCDataLoss:           "DATA_LOSS",
+	RPCUnauthenticated:    "UNAUTHENTICATED",
+}
+
+func (c RPCCode) String() string {
+	if int(c) < len(rpcCodeNames) {
+		return rpcCodeNames[c]
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// rpcCodesByError 是精确的错误码到 RPCCode 的映射, 优先于按 HTTP 状态的映射
+var rpcCodesByError = map[int]RPCCode{
+	ErrTimeout.Code:        RPCDeadlineExceeded,
+	ErrUnauthorized.Code:   RPCUnauthenticated,
+	ErrPermission.Code:     RPCPermissionDenied,
+	ErrPending.Code:        RPCUnavailable,
+	ErrMultipleError.Code:  RPCUnknown,
+	ErrInterruptError.Code: RPCCanceled,
+	ErrNetworkError.Code:   RPCUnavailable,
+	ErrAlreadyClosed.Code:  RPCFailedPrecondition,
+	ErrAlreadyStart.Code:   RPCFailedPrecondition,
+	ErrTableNotExists.Code: RPCNotFound,
+	ErrResultEmpty.Code:    RPCNotFound,
+	ErrConflict.Code:       RPCAlreadyExists,
+}
+
+// rpcCodesByStatus 是 HTTP 状态到 RPCCode 的映射
+var rpcCodesByStatus = map[int]RPCCode{
+	http.StatusOK:                           RPCOK,
+	http.StatusNoContent:                    RPCOK,
+	http.StatusBadRequest:                   RPCInvalidArgument,
+	http.StatusUnauthorized:                 RPCUnauthenticated,
+	http.StatusForbidden:                    RPCPermissionDenied,
+	http.StatusNotFound:                     RPCNotFound,
+	http.StatusNotAcceptable:                RPCInvalidArgument,
+	http.StatusRequestTimeout:               RPCDeadlineExceeded,
+	http.StatusConflict:                     RPCAborted,
+	http.StatusPreconditionFailed:           RPCFailedPrecondition,
+	http.StatusRequestedRangeNotSatisfiable: RPCOutOfRange,
+	http.StatusTooManyRequests:              RPCResourceExhausted,
+	499:                                     RPCCanceled,
+	http.StatusInternalServerError:          RPCInternal,
+	http.StatusNotImplemented:               RPCUnimplemented,
+	http.StatusBadGateway:                   RPCUnavailable,
+	http.StatusServiceUnavailable:           RPCUnavailable,
+	http.StatusGatewayTimeout:               RPCDeadlineExceeded,
+	ErrTypeError.HTTPCode():                 RPCInvalidArgument,
+	ErrValueNull.HTTPCode():                 RPCInvalidArgument,
+	ErrPending.HTTPCode():                   RPCUnavailable,
+	ErrMultipleError.HTTPCode():             RPCUnknown,
+}
+
+// rpcToErrorCode 是 RPCCode 到本包错误码的映射
+var rpcToErrorCode = [...]int{
+	RPCOK:                 http.StatusOK,
+	RPCCanceled:           ErrInterruptError.Code,
+	RPCUnknown:            http.StatusInternalServerError,
+	RPCInvalidArgument:    ErrBadArgument.Code,
+	RPCDeadlineExceeded:   ErrTimeout.Code,
+	RPCNotFound:           ErrNotFound.Code,
+	RPCAlreadyExists:      ErrConflict.Code,
+	RPCPermissionDenied:   ErrPermission.Code,
+	RPCResourceExhausted:  http.StatusTooManyRequests,
+	RPCFailedPrecondition: http.StatusPreconditionFailed,
+	RPCAborted:            http.StatusConflict,
+	RPCOutOfRange:         http.StatusRequestedRangeNotSatisfiable,
+	RPCUnimplemented:      ErrNotImplemented.Code,
+	RPCInternal:           http.StatusInternalServerError,
+	RPCUnavailable:        http.StatusServiceUnavailable,
+	RPCDataLoss:           http.StatusInternalServerError,
+	RPCUnauthenticated:    ErrUnauthorized.Code,
+}
+
+// ToRPCCode 将本包的错误码 (code 或 code/1000 的 HTTP 状态) 转换成 RPCCode
+func ToRPCCode(code int) RPCCode {
+	if c, ok := rpcCodesByError[code]; ok {
+		return c
+	}
+	status := ToHttpCode(code)
+	if c, ok := rpcCodesByStatus[status]; ok {
+		return c
+	}
+	switch {
+	case status >= 200 && status < 300:
+		return RPCOK
+	case status >= 400 && status < 500:
+		return RPCFailedPrecondition
+	case status >= 500:
+		return RPCInternal
+	}
+	return RPCUnknown
+}
+
+// FromRPCCode 将 RPCCode 转换成本包的错误码
+func FromRPCCode(c RPCCode) int {
+	if int(c) < len(rpcToErrorCode) {
+		return rpcToErrorCode[c]
+	}
+	return http.StatusInternalServerError
+}
+
+// Status 与 google.rpc.Status 的结构相同
+type Status struct {
+	Code    RPCCode       `json:"code"`
+	Message string        `json:"message,omitempty"`
+	Details []interface{} `json:"details,omitempty"`
+}
+
+// ToStatus 将 err 转换成 Status, err 为 nil 时返回 RPCOK
+//
+// Details 中第一个元素是对应的 *Error, 这样原始的错误码、字段等信息可以在另一端还原。
+func ToStatus(err error) *Status {
+	if err == nil {
+		return &Status{Code: RPCOK}
+	}
+	if s, ok := err.(*Status); ok {
+		return s
+	}
+
+	e := ToError(err)
+	return &Status{
+		Code:    ToRPCCode(e.Code),
+		Message: e.Error(),
+		Details: []interface{}{e},
+	}
+}
+
+// Error 实现 error 接口
+func (s *Status) Error() string {
+	return "rpc error: code = " + s.Code.String() + " desc = " + s.Message
+}
+
+// Err 将 Status 转换成 error, Code 为 RPCOK 时返回 nil
+//
+// Details 中的 *Error 优先返回, 经过 JSON 传输后它是一个 map, 这时会被解码回 *Error。
+func (s *Status) Err() error {
+	if s == nil || s.Code == RPCOK {
+		return nil
+	}
+	for _, detail := range s.Details {
+		switch d := detail.(type) {
+		case *Error:
+			if d != nil {
+				return d
+			}
+		case Error:
+			return &d
+		case map[string]interface{}, json.RawMessage:
+			var e Error
+			if problemExtension(d, &e) && e.Code != 0 {
+				return &e
+			}
+		}
+	}
+	return &Error{Code: FromRPCCode(s.Code), Message: s.Message}
+}
+
+func (s *Status) ErrorCode() int {
+	return FromRPCCode(s.Code)
+}
+
+func (s *Status) HTTPCode() int {
+	return ToHttpCode(FromRPCCode(s.Code))
+}
@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStatusErrOK(t *testing.T) {
+	var err error = (&Status{Code: RPCOK}).Err()
+	if err != nil {
+		t.Fatalf("got %#v, want nil", err)
+	}
+	if err = ToStatus(nil).Err(); err != nil {
+		t.Fatalf("got %#v, want nil", err)
+	}
+}
+
+func TestStatusErrAfterJSON(t *testing.T) {
+	s := ToStatus(BadArgument("age", 3))
+	bs, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Status
+	if err := json.Unmarshal(bs, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Code != RPCInvalidArgument {
+		t.Errorf("rpc code: got %v", decoded.Code)
+	}
+
+	e, ok := decoded.Err().(*Error)
+	if !ok {
+		t.Fatalf("got %T, want *Error", decoded.Err())
+	}
+	if e.Code != ErrBadArgument.Code {
+		t.Errorf("code: got %d, want %d", e.Code, ErrBadArgument.Code)
+	}
+	if e.Message != s.Details[0].(*Error).Message {
+		t.Errorf("message: got %q", e.Message)
+	}
+}
+
+func TestStatusErrWithoutDetails(t *testing.T) {
+	e, ok := (&Status{Code: RPCNotFound, Message: "gone"}).Err().(*Error)
+	if !ok || e.Code != FromRPCCode(RPCNotFound) || e.Message != "gone" {
+		t.Fatalf("got %#v", e)
+	}
+}
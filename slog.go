@@ -0,0 +1,184 @@
+package errors
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+)
+
+var _ slog.LogValuer = &Error{}
+var _ slog.LogValuer = errwrap{}
+var _ slog.LogValuer = &WithSQL{}
+var _ slog.LogValuer = &withStack{}
+
+// LogValue 实现 slog.LogValuer, 输出一个包含 code, http_status, message, details,
+// fields, retryable, retry_after, internals, cause 和 stack 的 group
+func (err *Error) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Int("code", err.Code),
+		slog.Int("http_status", err.HTTPCode()),
+		slog.String("message", err.Message),
+	}
+	if err.Details != "" {
+		attrs = append(attrs, slog.String("details", err.Details))
+	}
+	if len(err.Fields) > 0 || len(err.Values) > 0 {
+		fields := make([]slog.Attr, 0, len(err.Fields)+len(err.Values))
+		for key, values := range err.Fields {
+			// WithField 会把带类型的值同时放在 Fields 中, 这时只输出 Values 中的
+			if _, ok := err.Values[key]; !ok {
+				fields = append(fields, slog.Any(key, values))
+			}
+		}
+		for key, value := range err.Values {
+			fields = append(fields, slog.Any(key, value))
+		}
+		attrs = append(attrs, slog.Attr{Key: "fields", Value: slog.GroupValue(fields...)})
+	}
+	if err.Retry != nil {
+		attrs = append(attrs, slog.Bool("retryable", err.Retry.Retryable))
+		if err.Retry.After > 0 {
+			attrs = append(attrs, slog.Duration("retry_after", err.Retry.After))
+		}
+	}
+	if len(err.Internals) > 0 {
+		internals := make([]slog.Attr, len(err.Internals))
+		for idx := range err.Internals {
+			internals[idx] = slog.Attr{Key: strconv.Itoa(idx), Value: err.Internals[idx].LogValue()}
+		}
+		attrs = append(attrs, slog.Attr{Key: "internals", Value: slog.GroupValue(internals...)})
+	}
+	if _, ok := err.Cause.(errorList); !ok && err.Cause != nil {
+		attrs = append(attrs, slog.Attr{Key: "cause", Value: ErrorLogValue(err.Cause)})
+	}
+	return slog.GroupValue(appendStackAttr(attrs, err.stack)...)
+}
+
+func (e errwrap) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("message", e.Error()),
+		{Key: "cause", Value: ErrorLogValue(e.err)},
+	}
+	return slog.GroupValue(appendStackAttr(attrs, e.frames())...)
+}
+
+func (w *WithSQL) LogValue() slog.Value {
+	sql, args := w.display()
+	attrs := []slog.Attr{
+		slog.String("message", w.Error()),
+		slog.String("sql", sql),
+	}
+	if len(args) > 0 {
+		attrs = append(attrs, slog.Any("args", args))
+	}
+	if w.Err != nil {
+		attrs = append(attrs, slog.Attr{Key: "cause", Value: ErrorLogValue(w.Err)})
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func (w *withStack) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("message", w.err.Error()),
+		{Key: "cause", Value: ErrorLogValue(w.err)},
+	}
+	return slog.GroupValue(appendStackAttr(attrs, w.pcs)...)
+}
+
+func appendStackAttr(attrs []slog.Attr, pcs []uintptr) []slog.Attr {
+	if len(pcs) == 0 {
+		return attrs
+	}
+	return append(attrs, slog.Any("stack", stackStrings(pcs)))
+}
+
+func stackStrings(pcs []uintptr) []string {
+	frames := StackFrames(&withStack{pcs: pcs})
+	list := make([]string, len(frames))
+	for idx, frame := range frames {
+		list[idx] = frame.Function + " " + frame.File + ":" + strconv.Itoa(frame.Line)
+	}
+	return list
+}
+
+// ErrorLogValue 将任意的 error 转换成 slog.Value, 实现了 slog.LogValuer 的 error 使用它自己的 LogValue,
+// 其它的 error 输出 message, 以及能从错误链中找到的 code, http_status 和 stack
+func ErrorLogValue(err error) slog.Value {
+	if err == nil {
+		return slog.Value{}
+	}
+	if lv, ok := err.(slog.LogValuer); ok {
+		return lv.LogValue()
+	}
+
+	attrs := []slog.Attr{
+		slog.String("message", err.Error()),
+	}
+	if ec, ok := GetErrorCode(err); ok {
+		attrs = append(attrs, slog.Int("code", ec))
+	}
+	if hc, ok := GetHttpCode(err); ok {
+		attrs = append(attrs, slog.Int("http_status", hc))
+	}
+	if cause := Unwrap(err); cause != nil {
+		attrs = append(attrs, slog.Attr{Key: "cause", Value: ErrorLogValue(cause)})
+	} else if st := causeStackTrace(err); len(st) > 0 {
+		pcs := make([]uintptr, len(st))
+		for idx, f := range st {
+			pcs[idx] = uintptr(f)
+		}
+		attrs = appendStackAttr(attrs, pcs)
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// NewSlogHandler 包装一个 slog.Handler, 将所有值为 error 的属性用 ErrorLogValue 展开
+func NewSlogHandler(next slog.Handler) slog.Handler {
+	return &slogHandler{next: next}
+}
+
+type slogHandler struct {
+	next slog.Handler
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		r.AddAttrs(expandErrorAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, r)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	expanded := make([]slog.Attr, len(attrs))
+	for idx, attr := range attrs {
+		expanded[idx] = expandErrorAttr(attr)
+	}
+	return &slogHandler{next: h.next.WithAttrs(expanded)}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	return &slogHandler{next: h.next.WithGroup(name)}
+}
+
+func expandErrorAttr(attr slog.Attr) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok && err != nil {
+			return slog.Attr{Key: attr.Key, Value: ErrorLogValue(err)}
+		}
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		expanded := make([]slog.Attr, len(group))
+		for idx := range group {
+			expanded[idx] = expandErrorAttr(group[idx])
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(expanded...)}
+	}
+	return attr
+}
@@ -0,0 +1,122 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func logJSON(t *testing.T, handler func(slog.Handler) slog.Handler, args ...interface{}) map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	var h slog.Handler = slog.NewJSONHandler(&buf, nil)
+	if handler != nil {
+		h = handler(h)
+	}
+	slog.New(h).Info("failed", args...)
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("%s: %v", buf.String(), err)
+	}
+	return m
+}
+
+func TestErrorLogValue(t *testing.T) {
+	withStackPolicy(t, StackNever)
+
+	e := NewError(ErrRecordNotFound.Code, "user not found")
+	e.Details = "id=1"
+	e.WithValidationError("name", "required")
+	e.Values = Attrs{"id": 1}
+	e.Retry = &RetryHint{Retryable: true, After: 2e9}
+	e.Cause = io.EOF
+
+	m := logJSON(t, nil, "error", e)
+	group, ok := m["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("error should be a group: %#v", m["error"])
+	}
+	want := map[string]interface{}{
+		"code":        float64(ErrRecordNotFound.Code),
+		"http_status": float64(404),
+		"message":     "user not found",
+		"details":     "id=1",
+		"retryable":   true,
+		"retry_after": float64(2e9),
+		"fields": map[string]interface{}{
+			"name": []interface{}{"required"},
+			"id":   float64(1),
+		},
+		"cause": map[string]interface{}{"message": "EOF"},
+	}
+	if got, _ := json.Marshal(group); string(got) != mustMarshal(want) {
+		t.Errorf("got %s\nwant %s", got, mustMarshal(want))
+	}
+
+	m = logJSON(t, nil, "error", Concat(*NewError(ErrNotFound.Code, "a"), *NewError(ErrConflict.Code, "b")))
+	internals, _ := m["error"].(map[string]interface{})["internals"].(map[string]interface{})
+	if len(internals) != 2 || internals["1"].(map[string]interface{})["message"] != "b" {
+		t.Errorf("internals got %#v", m["error"])
+	}
+}
+
+func TestErrorLogValueStack(t *testing.T) {
+	withStackPolicy(t, StackAlways)
+
+	m := logJSON(t, nil, "error", Wrap(io.EOF, "read"))
+	group := m["error"].(map[string]interface{})
+	if group["message"] != "read: EOF" {
+		t.Errorf("message got %#v", group["message"])
+	}
+	if stack, ok := group["stack"].([]interface{}); !ok || len(stack) == 0 {
+		t.Errorf("stack got %#v", group["stack"])
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	err := fmt.Errorf("load: %w", ErrRecordNotFound)
+
+	// 没有包装时 error 只输出消息
+	m := logJSON(t, nil, "error", err)
+	if m["error"] != "load: "+ErrRecordNotFound.Message {
+		t.Errorf("got %#v", m["error"])
+	}
+
+	m = logJSON(t, NewSlogHandler, "error", err, slog.Group("req", "error", io.EOF), "n", 1)
+	group, ok := m["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("error should be expanded: %#v", m["error"])
+	}
+	if group["code"] != float64(ErrRecordNotFound.Code) || group["http_status"] != float64(404) {
+		t.Errorf("got %#v", group)
+	}
+	if cause, _ := group["cause"].(map[string]interface{}); cause["message"] != ErrRecordNotFound.Message {
+		t.Errorf("cause got %#v", group["cause"])
+	}
+	req, _ := m["req"].(map[string]interface{})
+	if e, _ := req["error"].(map[string]interface{}); e["message"] != "EOF" {
+		t.Errorf("errors in groups should be expanded: %#v", m["req"])
+	}
+	if m["n"] != float64(1) {
+		t.Errorf("other attrs should be kept: %#v", m["n"])
+	}
+
+	// WithAttrs 中的 error 也被展开
+	var buf bytes.Buffer
+	slog.New(NewSlogHandler(slog.NewJSONHandler(&buf, nil))).With("error", err).Info("failed")
+	if !bytes.Contains(buf.Bytes(), []byte(fmt.Sprintf(`"error":{"message":"load: %s","code":%d`, ErrRecordNotFound.Message, ErrRecordNotFound.Code))) {
+		t.Errorf("WithAttrs got %s", buf.String())
+	}
+}
+
+func mustMarshal(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(bs)
+}
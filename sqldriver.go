@@ -0,0 +1,335 @@
+package errors
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+)
+
+// WrapSQLDriver 包装一个 driver.Driver, 它返回的连接上的 Exec, Query, Prepare, Begin,
+// Commit 和 Rollback 的错误都会用 WrapSQLError 带上 SQL 语句和参数
+//
+//	sql.Register("postgres-errors", errors.WrapSQLDriver(&pq.Driver{}))
+//
+// 错误按原样放在 *WithSQL 中, GetErrorCode, ToError 和 IsRetryable 等用 ClassifySQLError
+// 和内置的分类 (如 sql.ErrTxDone, sql.ErrConnDone) 识别它们。sql.ErrNoRows 由 *sql.Row 的
+// Scan 返回, 不经过驱动, 需要时自己用 WrapSQLError 包装。
+//
+// driver.ErrSkip, driver.ErrBadConn, driver.ErrRemoveArgument 和 io.EOF 是 database/sql
+// 与驱动之间的约定, 它们不会被包装。Rows.Next 返回的错误也不会被包装。
+func WrapSQLDriver(d driver.Driver) driver.Driver {
+	return &sqlDriver{driver: d}
+}
+
+// WrapSQLConnector 与 WrapSQLDriver 相同, 只是包装的是 driver.Connector, 用于 sql.OpenDB
+func WrapSQLConnector(c driver.Connector) driver.Connector {
+	return &sqlConnector{connector: c, driver: &sqlDriver{driver: c.Driver()}}
+}
+
+// wrapDriverError 用 WrapSQLError 包装驱动返回的错误, database/sql 需要识别的错误除外
+func wrapDriverError(err error, query string, args []driver.NamedValue) error {
+	switch err {
+	case nil, driver.ErrSkip, driver.ErrBadConn, driver.ErrRemoveArgument, io.EOF:
+		return err
+	}
+	if Is(err, driver.ErrBadConn) {
+		return err
+	}
+	var values []interface{}
+	if len(args) > 0 {
+		values = make([]interface{}, len(args))
+		for idx := range args {
+			values[idx] = args[idx].Value
+		}
+	}
+	return WrapSQLError(err, query, values)
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for idx, arg := range args {
+		named[idx] = driver.NamedValue{Ordinal: idx + 1, Value: arg}
+	}
+	return named
+}
+
+func toValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for idx, arg := range args {
+		if arg.Name != "" {
+			return nil, New("sql: driver does not support the use of Named Parameters")
+		}
+		values[idx] = arg.Value
+	}
+	return values, nil
+}
+
+type sqlDriver struct {
+	driver driver.Driver
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{conn: conn}, nil
+}
+
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlConnector{connector: connector, driver: d}, nil
+	}
+	return &sqlConnector{connector: dsnConnector{name: name, driver: d.driver}, driver: d}, nil
+}
+
+// dsnConnector 用于没有实现 driver.DriverContext 的驱动
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type sqlConnector struct {
+	connector driver.Connector
+	driver    *sqlDriver
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{conn: conn}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (c *sqlConnector) Close() error {
+	if closer, ok := c.connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+type sqlConn struct {
+	conn driver.Conn
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.conn.Prepare(query)
+	if err != nil {
+		return nil, wrapDriverError(err, query, nil)
+	}
+	return &sqlStmt{stmt: stmt, conn: c.conn, query: query}, nil
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	stmt, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, wrapDriverError(err, query, nil)
+	}
+	return &sqlStmt{stmt: stmt, conn: c.conn, query: query}, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	tx, err := c.conn.Begin()
+	if err != nil {
+		return nil, wrapDriverError(err, "BEGIN", nil)
+	}
+	return &sqlTx{tx: tx}, nil
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	bt, ok := c.conn.(driver.ConnBeginTx)
+	if !ok {
+		// 与 database/sql 相同, 不支持 BeginTx 的驱动只能使用默认的选项
+		if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+			return nil, New("sql: driver does not support non-default isolation level or read-only transactions")
+		}
+		return c.Begin()
+	}
+	tx, err := bt.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, wrapDriverError(err, "BEGIN", nil)
+	}
+	return &sqlTx{tx: tx}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	var err error
+	switch x := c.conn.(type) {
+	case driver.ExecerContext:
+		result, err = x.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		values, err = toValues(args)
+		if err != nil {
+			return nil, err
+		}
+		result, err = x.Exec(query, values)
+	default:
+		return nil, driver.ErrSkip
+	}
+	if err != nil {
+		return nil, wrapDriverError(err, query, args)
+	}
+	return result, nil
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	var err error
+	switch x := c.conn.(type) {
+	case driver.QueryerContext:
+		rows, err = x.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		values, err = toValues(args)
+		if err != nil {
+			return nil, err
+		}
+		rows, err = x.Query(query, values)
+	default:
+		return nil, driver.ErrSkip
+	}
+	if err != nil {
+		return nil, wrapDriverError(err, query, args)
+	}
+	return rows, nil
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	stmt  driver.Stmt
+	conn  driver.Conn
+	query string
+}
+
+func (s *sqlStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *sqlStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	result, err := s.stmt.Exec(args)
+	if err != nil {
+		return nil, wrapDriverError(err, s.query, toNamedValues(args))
+	}
+	return result, nil
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.stmt.Query(args)
+	if err != nil {
+		return nil, wrapDriverError(err, s.query, toNamedValues(args))
+	}
+	return rows, nil
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := s.stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := toValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Exec(values)
+	}
+	result, err := ec.ExecContext(ctx, args)
+	if err != nil {
+		return nil, wrapDriverError(err, s.query, args)
+	}
+	return result, nil
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := s.stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := toValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Query(values)
+	}
+	rows, err := qc.QueryContext(ctx, args)
+	if err != nil {
+		return nil, wrapDriverError(err, s.query, args)
+	}
+	return rows, nil
+}
+
+// CheckNamedValue database/sql 优先使用 Stmt 的 NamedValueChecker, 所以这里要依次尝试 Stmt 和 Conn 的
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	if checker, ok := s.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlTx struct {
+	tx driver.Tx
+}
+
+func (t *sqlTx) Commit() error {
+	return wrapDriverError(t.tx.Commit(), "COMMIT", nil)
+}
+
+func (t *sqlTx) Rollback() error {
+	return wrapDriverError(t.tx.Rollback(), "ROLLBACK", nil)
+}
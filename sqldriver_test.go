@@ -0,0 +1,226 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+// fakeConn 只实现 driver.Conn, database/sql 会用 Prepare 执行语句
+type fakeConn struct {
+	prepareErr error
+	beginErr   error
+	stmtErr    error
+	txErr      error
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if c.prepareErr != nil {
+		return nil, c.prepareErr
+	}
+	return &fakeStmt{err: c.stmtErr}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if c.beginErr != nil {
+		return nil, c.beginErr
+	}
+	return &fakeTx{err: c.txErr}, nil
+}
+
+// fakeContextConn 实现了 ExecerContext 和 QueryerContext
+type fakeContextConn struct {
+	fakeConn
+	execErr error
+}
+
+func (c *fakeContextConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return nil, c.execErr
+}
+
+func (c *fakeContextConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return nil, c.execErr
+}
+
+// fakeStmt 只实现 driver.Stmt, 没有 StmtExecContext 和 StmtQueryContext
+type fakeStmt struct {
+	err error
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, s.err
+}
+
+type fakeTx struct {
+	err error
+}
+
+func (tx *fakeTx) Commit() error   { return tx.err }
+func (tx *fakeTx) Rollback() error { return tx.err }
+
+type fakeDriver struct {
+	conn driver.Conn
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return d.conn, nil
+}
+
+type fakeDriverError struct{ msg string }
+
+func (e *fakeDriverError) Error() string { return e.msg }
+
+func openFakeDB(t *testing.T, conn driver.Conn) *sql.DB {
+	db := sql.OpenDB(WrapSQLConnector(dsnConnector{driver: &fakeDriver{conn: conn}}))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func assertWithSQL(t *testing.T, err, cause error, query string, args []interface{}) {
+	t.Helper()
+	w, ok := err.(*WithSQL)
+	if !ok {
+		t.Fatalf("got %T (%v), want *WithSQL", err, err)
+	}
+	if w.Err != cause {
+		t.Errorf("cause: got %#v, want %#v", w.Err, cause)
+	}
+	if w.SqlStr != query {
+		t.Errorf("sql: got %q, want %q", w.SqlStr, query)
+	}
+	if !reflect.DeepEqual(w.Args, args) {
+		t.Errorf("args: got %#v, want %#v", w.Args, args)
+	}
+}
+
+func TestSQLDriverStmtErrors(t *testing.T) {
+	boom := &fakeDriverError{"boom"}
+	db := openFakeDB(t, &fakeConn{stmtErr: boom})
+
+	// 驱动没有实现 ExecerContext, sqlConn 返回 driver.ErrSkip, database/sql 改用 Prepare,
+	// 然后 sqlStmt 因为驱动没有实现 StmtExecContext 而调用 Exec
+	_, err := db.Exec("UPDATE t SET a = ? WHERE id = ?", "x", 1)
+	assertWithSQL(t, err, boom, "UPDATE t SET a = ? WHERE id = ?", []interface{}{"x", int64(1)})
+
+	_, err = db.Query("SELECT * FROM t WHERE id = ?", 2)
+	assertWithSQL(t, err, boom, "SELECT * FROM t WHERE id = ?", []interface{}{int64(2)})
+
+	stmt, err := db.Prepare("DELETE FROM t WHERE id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(3)
+	assertWithSQL(t, err, boom, "DELETE FROM t WHERE id = ?", []interface{}{int64(3)})
+}
+
+func TestSQLDriverPrepareError(t *testing.T) {
+	boom := &fakeDriverError{"syntax error"}
+	db := openFakeDB(t, &fakeConn{prepareErr: boom})
+
+	_, err := db.Prepare("SELEC 1")
+	assertWithSQL(t, err, boom, "SELEC 1", nil)
+
+	_, err = db.Exec("SELEC ?", 1)
+	assertWithSQL(t, err, boom, "SELEC ?", nil)
+}
+
+func TestSQLDriverTxErrors(t *testing.T) {
+	boom := &fakeDriverError{"begin failed"}
+	db := openFakeDB(t, &fakeConn{beginErr: boom})
+	_, err := db.Begin()
+	assertWithSQL(t, err, boom, "BEGIN", nil)
+
+	_, err = db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if _, ok := err.(*WithSQL); ok || err == nil {
+		t.Errorf("BeginTx with options: got %#v", err)
+	}
+
+	boom = &fakeDriverError{"commit failed"}
+	db = openFakeDB(t, &fakeConn{txErr: boom})
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Commit()
+	assertWithSQL(t, err, boom, "COMMIT", nil)
+}
+
+func TestSQLDriverExecerContext(t *testing.T) {
+	boom := &fakeDriverError{"boom"}
+	conn := &sqlConn{conn: &fakeContextConn{execErr: boom}}
+	args := []driver.NamedValue{{Ordinal: 1, Value: int64(1)}, {Name: "b", Ordinal: 2, Value: "x"}}
+
+	_, err := conn.ExecContext(context.Background(), "UPDATE t SET b = @b WHERE id = ?", args)
+	assertWithSQL(t, err, boom, "UPDATE t SET b = @b WHERE id = ?", []interface{}{int64(1), "x"})
+
+	_, err = conn.QueryContext(context.Background(), "SELECT 1", nil)
+	assertWithSQL(t, err, boom, "SELECT 1", nil)
+}
+
+func TestSQLDriverPassthrough(t *testing.T) {
+	ctx := context.Background()
+	for _, e := range []error{driver.ErrSkip, driver.ErrBadConn, driver.ErrRemoveArgument} {
+		conn := &sqlConn{conn: &fakeContextConn{execErr: e}}
+		if _, err := conn.ExecContext(ctx, "SELECT 1", nil); err != e {
+			t.Errorf("ExecContext: got %#v, want %#v", err, e)
+		}
+		if _, err := conn.QueryContext(ctx, "SELECT 1", nil); err != e {
+			t.Errorf("QueryContext: got %#v, want %#v", err, e)
+		}
+	}
+
+	// 驱动没有实现 ExecerContext 和 QueryerContext 时返回 driver.ErrSkip
+	conn := &sqlConn{conn: &fakeConn{}}
+	if _, err := conn.ExecContext(ctx, "SELECT 1", nil); err != driver.ErrSkip {
+		t.Errorf("ExecContext: got %#v, want driver.ErrSkip", err)
+	}
+	if _, err := conn.QueryContext(ctx, "SELECT 1", nil); err != driver.ErrSkip {
+		t.Errorf("QueryContext: got %#v, want driver.ErrSkip", err)
+	}
+
+	bad := Wrap(driver.ErrBadConn, "read")
+	conn = &sqlConn{conn: &fakeConn{stmtErr: bad}}
+	stmt, err := conn.Prepare("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stmt.Exec(nil)
+	if _, ok := err.(*WithSQL); ok || !Is(err, driver.ErrBadConn) {
+		t.Errorf("Exec: got %#v, want the wrapped driver.ErrBadConn", err)
+	}
+}
+
+func TestSQLStmtNamedFallback(t *testing.T) {
+	conn := &sqlConn{conn: &fakeConn{}}
+	stmt, err := conn.Prepare("SELECT @a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := []driver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}}
+	if _, err := stmt.(driver.StmtExecContext).ExecContext(context.Background(), named); err == nil {
+		t.Error("ExecContext: named args should be rejected by a driver without StmtExecContext")
+	}
+	if _, err := stmt.(driver.StmtQueryContext).QueryContext(context.Background(), named); err == nil {
+		t.Error("QueryContext: named args should be rejected by a driver without StmtQueryContext")
+	}
+
+	result, err := stmt.(driver.StmtExecContext).ExecContext(context.Background(), []driver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+	if err != nil || result == nil {
+		t.Errorf("ExecContext: got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,225 @@
+package errors
+
+import (
+	"reflect"
+	"strings"
+)
+
+// sqlErrorKind 一个数据库错误对应的预定义错误, 以及它是否可以重试
+type sqlErrorKind struct {
+	err       *Error
+	retryable bool
+}
+
+// sqlStates Postgres 等使用 SQLSTATE 的数据库的错误
+var sqlStates = map[string]sqlErrorKind{
+	"23505": {err: ErrConflict},                      // unique_violation
+	"23503": {err: ErrConflict},                      // foreign_key_violation
+	"23P01": {err: ErrConflict},                      // exclusion_violation
+	"23502": {err: ErrRequired},                      // not_null_violation
+	"42P01": {err: ErrTableNotExists},                // undefined_table
+	"42501": {err: ErrPermission},                    // insufficient_privilege
+	"28000": {err: ErrPermission},                    // invalid_authorization_specification
+	"28P01": {err: ErrPermission},                    // invalid_password
+	"40001": {err: ErrConflict, retryable: true},     // serialization_failure
+	"40P01": {err: ErrConflict, retryable: true},     // deadlock_detected
+	"55P03": {err: ErrConflict, retryable: true},     // lock_not_available
+	"57014": {err: ErrTimeout},                       // query_canceled
+	"57P01": {err: ErrNetworkError, retryable: true}, // admin_shutdown
+	"57P02": {err: ErrNetworkError, retryable: true}, // crash_shutdown
+	"57P03": {err: ErrNetworkError, retryable: true}, // cannot_connect_now
+}
+
+// sqlStateClasses 按 SQLSTATE 的前两位分类, 在 sqlStates 中找不到时使用
+var sqlStateClasses = map[string]sqlErrorKind{
+	"08": {err: ErrNetworkError, retryable: true}, // connection_exception
+	"23": {err: ErrConflict},                      // integrity_constraint_violation
+	"40": {err: ErrConflict, retryable: true},     // transaction_rollback
+}
+
+// mysqlErrors MySQL 的错误号
+var mysqlErrors = map[int64]sqlErrorKind{
+	1022: {err: ErrConflict},                      // ER_DUP_KEY
+	1062: {err: ErrConflict},                      // ER_DUP_ENTRY
+	1586: {err: ErrConflict},                      // ER_DUP_ENTRY_WITH_KEY_NAME
+	1216: {err: ErrConflict},                      // ER_NO_REFERENCED_ROW
+	1217: {err: ErrConflict},                      // ER_ROW_IS_REFERENCED
+	1451: {err: ErrConflict},                      // ER_ROW_IS_REFERENCED_2
+	1452: {err: ErrConflict},                      // ER_NO_REFERENCED_ROW_2
+	1146: {err: ErrTableNotExists},                // ER_NO_SUCH_TABLE
+	1048: {err: ErrRequired},                      // ER_BAD_NULL_ERROR
+	1364: {err: ErrRequired},                      // ER_NO_DEFAULT_FOR_FIELD
+	1044: {err: ErrPermission},                    // ER_DBACCESS_DENIED_ERROR
+	1045: {err: ErrPermission},                    // ER_ACCESS_DENIED_ERROR
+	1142: {err: ErrPermission},                    // ER_TABLEACCESS_DENIED_ERROR
+	1213: {err: ErrConflict, retryable: true},     // ER_LOCK_DEADLOCK
+	1205: {err: ErrTimeout, retryable: true},      // ER_LOCK_WAIT_TIMEOUT
+	1040: {err: ErrNetworkError, retryable: true}, // ER_CON_COUNT_ERROR
+	1053: {err: ErrNetworkError, retryable: true}, // ER_SERVER_SHUTDOWN
+	2002: {err: ErrNetworkError, retryable: true}, // CR_CONNECTION_ERROR
+	2003: {err: ErrNetworkError, retryable: true}, // CR_CONN_HOST_ERROR
+	2006: {err: ErrNetworkError, retryable: true}, // CR_SERVER_GONE_ERROR
+	2013: {err: ErrNetworkError, retryable: true}, // CR_SERVER_LOST
+}
+
+// sqliteErrors SQLite 的结果码, 先按扩展结果码查找, 找不到时再按主结果码 (低 8 位) 查找
+var sqliteErrors = map[int64]sqlErrorKind{
+	2067: {err: ErrConflict},                  // SQLITE_CONSTRAINT_UNIQUE
+	1555: {err: ErrConflict},                  // SQLITE_CONSTRAINT_PRIMARYKEY
+	787:  {err: ErrConflict},                  // SQLITE_CONSTRAINT_FOREIGNKEY
+	1299: {err: ErrRequired},                  // SQLITE_CONSTRAINT_NOTNULL
+	19:   {err: ErrConflict},                  // SQLITE_CONSTRAINT
+	3:    {err: ErrPermission},                // SQLITE_PERM
+	23:   {err: ErrPermission},                // SQLITE_AUTH
+	5:    {err: ErrConflict, retryable: true}, // SQLITE_BUSY
+	6:    {err: ErrConflict, retryable: true}, // SQLITE_LOCKED
+}
+
+// ClassifySQLError 将数据库驱动的错误分类成预定义的错误, 它不依赖任何驱动, 只按下面的方式识别:
+//
+//	SQLState() string 或字符串类型的 Code 字段   SQLSTATE, 如 Postgres (lib/pq, pgx)
+//	Number() uint16 或 MySQL 驱动的 Number 字段  MySQL 的错误号
+//	SQLite 驱动的 ExtendedCode, Code 字段或 Code() int  SQLite 的结果码
+//
+// 唯一键和外键冲突为 ErrConflict, 表不存在为 ErrTableNotExists, 非空约束为 ErrRequired,
+// 连接错误为 ErrNetworkError, 死锁和序列化失败为 ErrConflict, 但它们是可以重试的, 见 IsRetryable。
+// 它按错误链查找, 不是能识别的数据库错误时返回 false。
+func ClassifySQLError(err error) (*Error, bool) {
+	kind, ok := findSQLErrorKind(err)
+	if !ok {
+		return nil, false
+	}
+	return kind.err, true
+}
+
+func findSQLErrorKind(err error) (kind sqlErrorKind, found bool) {
+	Walk(err, func(e error) bool {
+		kind, found = classifySQL(e)
+		return found
+	})
+	return kind, found
+}
+
+// classifySQL 识别 err 本身, 不查找错误链
+func classifySQL(err error) (sqlErrorKind, bool) {
+	if x, ok := err.(interface{ SQLState() string }); ok {
+		if kind, ok := lookupSQLState(x.SQLState()); ok {
+			return kind, true
+		}
+	}
+	if x, ok := err.(interface{ Number() uint16 }); ok {
+		kind, ok := mysqlErrors[int64(x.Number())]
+		return kind, ok
+	}
+
+	rv := reflect.ValueOf(err)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return sqlErrorKind{}, false
+		}
+		rv = rv.Elem()
+	}
+	pkgPath := strings.ToLower(rv.Type().PkgPath())
+
+	if rv.Kind() == reflect.Struct {
+		if code := rv.FieldByName("Code"); code.IsValid() && code.Kind() == reflect.String {
+			if kind, ok := lookupSQLState(code.String()); ok {
+				return kind, true
+			}
+		}
+		// Number 和整数类型的 Code 字段太常见, 只在驱动的包中识别它们
+		if strings.Contains(pkgPath, "mysql") {
+			if number, ok := intField(rv, "Number"); ok {
+				kind, ok := mysqlErrors[number]
+				return kind, ok
+			}
+		}
+		if strings.Contains(pkgPath, "sqlite") {
+			if code, ok := intField(rv, "ExtendedCode"); ok {
+				if kind, ok := lookupSQLite(code, err); ok {
+					return kind, true
+				}
+			}
+			if code, ok := intField(rv, "Code"); ok {
+				return lookupSQLite(code, err)
+			}
+		}
+	}
+	if strings.Contains(pkgPath, "sqlite") {
+		if x, ok := err.(interface{ Code() int }); ok {
+			return lookupSQLite(int64(x.Code()), err)
+		}
+	}
+	return sqlErrorKind{}, false
+}
+
+func lookupSQLState(state string) (sqlErrorKind, bool) {
+	if !isSQLState(state) {
+		return sqlErrorKind{}, false
+	}
+	if kind, ok := sqlStates[state]; ok {
+		return kind, true
+	}
+	kind, ok := sqlStateClasses[state[:2]]
+	return kind, ok
+}
+
+// isSQLState 判断 s 是否是 5 个数字或大写字母组成的 SQLSTATE
+func isSQLState(s string) bool {
+	if len(s) != 5 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupSQLite(code int64, err error) (sqlErrorKind, bool) {
+	if kind, ok := sqliteErrors[code]; ok {
+		return kind, true
+	}
+	if kind, ok := sqliteErrors[code&0xff]; ok {
+		return kind, true
+	}
+	// SQLITE_ERROR 只能按消息区分表不存在
+	if code&0xff == 1 && strings.Contains(err.Error(), "no such table") {
+		return sqlErrorKind{err: ErrTableNotExists}, true
+	}
+	return sqlErrorKind{}, false
+}
+
+func intField(rv reflect.Value, name string) (int64, bool) {
+	field := rv.FieldByName(name)
+	if !field.IsValid() {
+		return 0, false
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(field.Uint()), true
+	}
+	return 0, false
+}
+
+// Fill 在 ToError 时将可以重试的数据库错误 (如死锁) 标记为可以重试
+func (w *WithSQL) Fill(e *Error) {
+	if e.Retry != nil {
+		return
+	}
+	if kind, ok := findSQLErrorKind(w.Err); ok && kind.retryable {
+		e.Retry = &RetryHint{Retryable: true}
+	}
+}
+
+func init() {
+	RegisterClassifier("sql", BuiltinClassifierPriority, func(err error) (int, bool) {
+		if e, ok := ClassifySQLError(err); ok {
+			return e.Code, true
+		}
+		return 0, false
+	})
+}
@@ -0,0 +1,146 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// pqError 与 lib/pq 的 *pq.Error 一样用字符串类型的 Code 字段保存 SQLSTATE
+type pqError struct {
+	Code    string
+	Message string
+}
+
+func (e *pqError) Error() string { return "pq: " + e.Message }
+
+// pgxError 与 pgconn.PgError 一样实现 SQLState()
+type pgxError struct{ state string }
+
+func (e *pgxError) Error() string    { return "ERROR (SQLSTATE " + e.state + ")" }
+func (e *pgxError) SQLState() string { return e.state }
+
+// mysqlNumberError 用 Number() 返回 MySQL 的错误号
+type mysqlNumberError struct{ number uint16 }
+
+func (e mysqlNumberError) Error() string  { return fmt.Sprintf("Error %d", e.number) }
+func (e mysqlNumberError) Number() uint16 { return e.number }
+
+func TestClassifySQLState(t *testing.T) {
+	tests := []struct {
+		state     string
+		want      *Error
+		retryable bool
+	}{
+		{"23505", ErrConflict, false},
+		{"23503", ErrConflict, false},
+		{"23502", ErrRequired, false},
+		{"42P01", ErrTableNotExists, false},
+		{"42501", ErrPermission, false},
+		{"40001", ErrConflict, true},
+		{"40P01", ErrConflict, true},
+		{"57014", ErrTimeout, false},
+		{"57P01", ErrNetworkError, true},
+		// 按前两位分类
+		{"08006", ErrNetworkError, true},
+		{"23514", ErrConflict, false},
+		{"40002", ErrConflict, true},
+	}
+	for _, test := range tests {
+		for _, err := range []error{&pqError{Code: test.state}, &pgxError{state: test.state}} {
+			wrapped := fmt.Errorf("query: %w", err)
+			if got, ok := ClassifySQLError(wrapped); !ok || got != test.want {
+				t.Errorf("%T %s: got %v %v, want %v", err, test.state, got, ok, test.want)
+			}
+			if code, _ := GetErrorCode(wrapped); code != test.want.Code {
+				t.Errorf("%T %s: GetErrorCode got %d", err, test.state, code)
+			}
+			if got := IsRetryable(wrapped); got != test.retryable {
+				t.Errorf("%T %s: IsRetryable got %v", err, test.state, got)
+			}
+		}
+	}
+
+	for _, state := range []string{"", "00000", "42601", "abcde", "2350", "235050"} {
+		if got, ok := ClassifySQLError(&pqError{Code: state}); ok {
+			t.Errorf("%q: got %v", state, got)
+		}
+	}
+}
+
+func TestClassifyMySQLNumber(t *testing.T) {
+	tests := []struct {
+		number    uint16
+		want      *Error
+		retryable bool
+	}{
+		{1062, ErrConflict, false},
+		{1452, ErrConflict, false},
+		{1146, ErrTableNotExists, false},
+		{1048, ErrRequired, false},
+		{1045, ErrPermission, false},
+		{1213, ErrConflict, true},
+		{1205, ErrTimeout, true},
+		{2006, ErrNetworkError, true},
+	}
+	for _, test := range tests {
+		err := Wrap(mysqlNumberError{test.number}, "insert")
+		if got, ok := ClassifySQLError(err); !ok || got != test.want {
+			t.Errorf("%d: got %v %v, want %v", test.number, got, ok, test.want)
+		}
+		if got := IsRetryable(err); got != test.retryable {
+			t.Errorf("%d: IsRetryable got %v", test.number, got)
+		}
+	}
+	if got, ok := ClassifySQLError(mysqlNumberError{1064}); ok {
+		t.Errorf("1064: got %v", got)
+	}
+}
+
+// sqliteFields 与 mattn/go-sqlite3 的 sqlite3.Error 的字段相同, 它不在 sqlite 驱动的包中,
+// 所以只能直接用 lookupSQLite 和 intField 测试
+type sqliteFields struct {
+	Code         int
+	ExtendedCode int
+	err          string
+}
+
+func (e sqliteFields) Error() string { return e.err }
+
+func TestClassifySQLite(t *testing.T) {
+	tests := []struct {
+		err       sqliteFields
+		want      *Error
+		retryable bool
+	}{
+		{sqliteFields{Code: 19, ExtendedCode: 2067}, ErrConflict, false},
+		{sqliteFields{Code: 19, ExtendedCode: 1299}, ErrRequired, false},
+		{sqliteFields{Code: 19, ExtendedCode: 275}, ErrConflict, false}, // SQLITE_CONSTRAINT_CHECK, 按主结果码
+		{sqliteFields{Code: 5, ExtendedCode: 517}, ErrConflict, true},   // SQLITE_BUSY_SNAPSHOT, 按主结果码
+		{sqliteFields{Code: 6, ExtendedCode: 262}, ErrConflict, true},   // SQLITE_LOCKED_SHAREDCACHE
+		{sqliteFields{Code: 23, ExtendedCode: 23}, ErrPermission, false},
+		{sqliteFields{Code: 1, ExtendedCode: 1, err: "no such table: users"}, ErrTableNotExists, false},
+	}
+	for _, test := range tests {
+		code, ok := intField(reflect.ValueOf(test.err), "ExtendedCode")
+		if !ok {
+			t.Fatal("ExtendedCode should be read")
+		}
+		kind, ok := lookupSQLite(code, test.err)
+		if !ok || kind.err != test.want || kind.retryable != test.retryable {
+			t.Errorf("%d: got %v %v %v, want %v", code, kind.err, kind.retryable, ok, test.want)
+		}
+	}
+
+	for _, code := range []int64{1, 0, 101} {
+		if kind, ok := lookupSQLite(code, io.EOF); ok {
+			t.Errorf("%d: got %v", code, kind.err)
+		}
+	}
+
+	// 不在 sqlite 驱动的包中时不识别整数类型的字段
+	if got, ok := ClassifySQLError(sqliteFields{Code: 19, ExtendedCode: 2067}); ok {
+		t.Errorf("got %v", got)
+	}
+}
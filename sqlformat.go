@@ -0,0 +1,514 @@
+package errors
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SQLDialect SQL 语句中参数占位符的风格
+type SQLDialect int
+
+const (
+	// DialectAuto 同时识别 ?, $1 和 :name (@name) 三种占位符, 这是默认值
+	DialectAuto SQLDialect = iota
+	// DialectQuestion 只识别 ?, 如 MySQL 和 SQLite
+	DialectQuestion
+	// DialectDollar 只识别 $1, 如 Postgres, 这时 ? 是 jsonb 的操作符
+	DialectDollar
+	// DialectNamed 只识别 :name, @name 和 :1, 如 Oracle 和 SQL Server
+	DialectNamed
+)
+
+// SQLFormatter 将参数代入 SQL 语句中, 生成用于显示 (日志和错误报告) 的 SQL, 它不能用于执行
+//
+// 需要隐藏的参数按位置 (RedactArgs) 或对应的列名 (RedactColumns) 指定, 列名按下面的方式确定:
+//
+//	col = ?, col <> ?, col LIKE ? 等比较和 UPDATE 中的 SET col = ?
+//	INSERT INTO t (a, b) VALUES (?, ?) 中 VALUES 里的位置对应的列
+//	:name, @name 和 sql.NamedArg 的名称
+//
+// 列名只能从上面这些简单的形式推断, 函数调用中的参数 (如 password = crypt($2, password) 中的 $2)
+// 找不到对应的列名, 不会按 RedactColumns 隐藏, 这时请用 RedactArgs 按位置指定。
+type SQLFormatter struct {
+	// Dialect 占位符的风格
+	Dialect SQLDialect
+	// MaxValueLen 每个参数最多显示的字符数, 超出部分用 ... 代替, 0 表示不截断
+	MaxValueLen int
+	// RedactArgs 需要隐藏的参数的位置, 从 1 开始, 与 $1 一致
+	RedactArgs []int
+	// RedactColumns 需要隐藏的列名, 列名包含其中任何一个 (不区分大小写) 就隐藏
+	RedactColumns []string
+	// Redacted 代替隐藏的参数的文本, 为空时是 '***'
+	Redacted string
+}
+
+// DefaultSQLFormatter *WithSQL 的 %+v 和 slog 输出使用的 SQLFormatter, 为 nil 时分别输出 SQL 和参数
+var DefaultSQLFormatter = &SQLFormatter{
+	MaxValueLen:   64,
+	RedactColumns: []string{"password", "passwd", "secret", "token", "api_key", "apikey", "credential"},
+}
+
+// display 返回 %+v 和 slog 中显示的 SQL 语句和参数, 参数都被代入 SQL 语句时不再单独显示
+func (w *WithSQL) display() (string, []string) {
+	f := DefaultSQLFormatter
+	if f == nil {
+		args := make([]string, len(w.Args))
+		for idx, arg := range w.Args {
+			args[idx] = fmt.Sprint(arg)
+		}
+		return w.SqlStr, args
+	}
+	s, used, _ := f.render(w.SqlStr, w.Args)
+	if used > 0 || len(w.Args) == 0 {
+		return s, nil
+	}
+	return s, f.FormatArgs(w.Args)
+}
+
+// goArgs 返回 %#v 中显示的参数, 与 display 一样隐藏敏感的参数
+func (w *WithSQL) goArgs() interface{} {
+	f := DefaultSQLFormatter
+	if f == nil {
+		return w.Args
+	}
+	args := f.FormatArgs(w.Args)
+	_, _, redacted := f.render(w.SqlStr, w.Args)
+	for idx := range args {
+		if redacted[idx] {
+			args[idx] = f.redacted()
+		}
+	}
+	return args
+}
+
+// Format 返回代入了参数的 SQL 语句, 找不到参数的占位符保持原样
+func (f *SQLFormatter) Format(sqlStr string, args []interface{}) string {
+	s, _, _ := f.render(sqlStr, args)
+	return s
+}
+
+// FormatArgs 返回每个参数显示的文本, 只按位置隐藏参数
+func (f *SQLFormatter) FormatArgs(args []interface{}) []string {
+	list := make([]string, len(args))
+	for idx, arg := range args {
+		if f.redactArg(idx) {
+			list[idx] = f.redacted()
+			continue
+		}
+		list[idx] = f.FormatValue(arg)
+	}
+	return list
+}
+
+// FormatValue 将一个参数转换成 SQL 字面量的形式
+func (f *SQLFormatter) FormatValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case sql.NamedArg:
+		return f.FormatValue(value.Value)
+	case driver.Valuer:
+		dv, err := value.Value()
+		if err != nil {
+			return f.quote(fmt.Sprintf("%v", v))
+		}
+		if _, ok := dv.(driver.Valuer); ok {
+			return f.quote(fmt.Sprintf("%v", dv))
+		}
+		return f.FormatValue(dv)
+	case string:
+		return f.quote(value)
+	case []byte:
+		if value == nil {
+			return "NULL"
+		}
+		return "X'" + f.truncate(strings.ToUpper(hex.EncodeToString(value))) + "'"
+	case bool:
+		if value {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		return f.quote(value.Format("2006-01-02 15:04:05.999999999Z07:00"))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(value)
+	case fmt.Stringer:
+		return f.quote(value.String())
+	}
+	return f.quote(fmt.Sprintf("%v", v))
+}
+
+func (f *SQLFormatter) quote(s string) string {
+	return "'" + strings.ReplaceAll(f.truncate(s), "'", "''") + "'"
+}
+
+func (f *SQLFormatter) truncate(s string) string {
+	if f.MaxValueLen <= 0 || utf8.RuneCountInString(s) <= f.MaxValueLen {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:f.MaxValueLen]) + "..."
+}
+
+func (f *SQLFormatter) redacted() string {
+	if f.Redacted == "" {
+		return "'***'"
+	}
+	return f.Redacted
+}
+
+func (f *SQLFormatter) redactArg(idx int) bool {
+	for _, pos := range f.RedactArgs {
+		if pos == idx+1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *SQLFormatter) redactColumn(name string) bool {
+	if name == "" {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, column := range f.RedactColumns {
+		if column != "" && strings.Contains(name, strings.ToLower(column)) {
+			return true
+		}
+	}
+	return false
+}
+
+// render 返回代入了参数的 SQL 语句, 被代入的占位符的个数, 以及每个参数是否被隐藏
+func (f *SQLFormatter) render(sqlStr string, args []interface{}) (string, int, []bool) {
+	tokens := tokenizeSQL(sqlStr, f.Dialect)
+	if f.Dialect == DialectAuto {
+		// 已经有 ? 或 $1 时, :name 和 @name 更可能是变量 (如 MySQL 的 @var), 不把它们当作占位符
+		positional := false
+		for _, tok := range tokens {
+			if tok.kind == sqlTokenPlaceholder && tok.name == "" {
+				positional = true
+				break
+			}
+		}
+		if positional {
+			for idx := range tokens {
+				if tokens[idx].kind == sqlTokenPlaceholder && tokens[idx].name != "" {
+					tokens[idx] = sqlToken{kind: sqlTokenWord, text: tokens[idx].text}
+				}
+			}
+		}
+	}
+	assignSQLColumns(tokens)
+
+	named := map[string]int{}
+	for idx, arg := range args {
+		if na, ok := arg.(sql.NamedArg); ok && na.Name != "" {
+			named[na.Name] = idx
+		}
+	}
+	// 没有 sql.NamedArg 时, 不同的名称按第一次出现的顺序对应参数
+	seen := map[string]int{}
+
+	redacted := make([]bool, len(args))
+	var sb strings.Builder
+	sb.Grow(len(sqlStr))
+	next, used := 0, 0
+	for idx := range tokens {
+		tok := &tokens[idx]
+		if tok.kind != sqlTokenPlaceholder {
+			sb.WriteString(tok.text)
+			continue
+		}
+
+		argIdx := tok.index
+		if tok.name != "" {
+			name := tok.name[1:]
+			if i, ok := named[name]; ok {
+				argIdx = i
+			} else {
+				if _, ok := seen[name]; !ok {
+					seen[name] = next
+					next++
+				}
+				argIdx = seen[name]
+			}
+		} else if argIdx < 0 {
+			argIdx = next
+			next++
+		}
+		if argIdx < 0 || argIdx >= len(args) {
+			sb.WriteString(tok.text)
+			continue
+		}
+
+		used++
+		column := tok.column
+		if column == "" && tok.name != "" {
+			column = tok.name[1:]
+		}
+		if na, ok := args[argIdx].(sql.NamedArg); ok && column == "" {
+			column = na.Name
+		}
+		if f.redactArg(argIdx) || f.redactColumn(column) {
+			redacted[argIdx] = true
+			sb.WriteString(f.redacted())
+		} else {
+			sb.WriteString(f.FormatValue(args[argIdx]))
+		}
+	}
+	return sb.String(), used, redacted
+}
+
+type sqlTokenKind int
+
+const (
+	sqlTokenOther sqlTokenKind = iota
+	sqlTokenSpace
+	sqlTokenWord
+	sqlTokenIdent
+	sqlTokenString
+	sqlTokenOperator
+	sqlTokenPlaceholder
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+
+	// 以下只用于占位符
+	index  int    // 位置参数的序号, 从 0 开始, -1 表示按出现的顺序
+	name   string // 命名参数的名称, 包括 : 或 @ 前缀
+	column string // 推断出的列名
+}
+
+func isSQLWordChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 0x80 ||
+		(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSQLNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tokenizeSQL 将 SQL 语句拆成简单的记号, 字符串、注释和带引号的标识符中的占位符不会被识别
+func tokenizeSQL(s string, dialect SQLDialect) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(s); {
+		start := i
+		c := s[i]
+		switch {
+		case c == '\'':
+			i = skipQuoted(s, i, '\'')
+			tokens = append(tokens, sqlToken{kind: sqlTokenString, text: s[start:i]})
+		case c == '"' || c == '`':
+			i = skipQuoted(s, i, c)
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, text: s[start:i]})
+		case c == '[' && dialect == DialectNamed:
+			i = skipQuoted(s, i, ']')
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, text: s[start:i]})
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(s)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenSpace, text: s[start:i]})
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(s)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenSpace, text: s[start:i]})
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenSpace, text: s[start:i]})
+		case c == '?' && (dialect == DialectAuto || dialect == DialectQuestion):
+			i++
+			tokens = append(tokens, sqlToken{kind: sqlTokenPlaceholder, text: "?", index: -1})
+		case c == '$' && i+1 < len(s) && isDigit(s[i+1]) && (dialect == DialectAuto || dialect == DialectDollar):
+			i++
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			n, _ := strconv.Atoi(s[start+1 : i])
+			tokens = append(tokens, sqlToken{kind: sqlTokenPlaceholder, text: s[start:i], index: n - 1})
+		case c == ':' && i+1 < len(s) && s[i+1] == ':':
+			// Postgres 的类型转换
+			i += 2
+			tokens = append(tokens, sqlToken{kind: sqlTokenOther, text: "::"})
+		case (c == ':' || c == '@') && i+1 < len(s) && isSQLNameStart(s[i+1]) &&
+			(dialect == DialectAuto || dialect == DialectNamed) &&
+			(start == 0 || !isSQLWordChar(s[start-1])):
+			i++
+			for i < len(s) && isSQLWordChar(s[i]) && s[i] != '.' {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenPlaceholder, text: s[start:i], name: s[start:i], index: -1})
+		case c == ':' && i+1 < len(s) && isDigit(s[i+1]) && dialect == DialectNamed:
+			i++
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			n, _ := strconv.Atoi(s[start+1 : i])
+			tokens = append(tokens, sqlToken{kind: sqlTokenPlaceholder, text: s[start:i], index: n - 1})
+		case c == '@' && i+1 < len(s) && s[i+1] == '@':
+			// SQL Server 的系统变量
+			i += 2
+			for i < len(s) && isSQLWordChar(s[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenWord, text: s[start:i]})
+		case isSQLWordChar(c):
+			for i < len(s) && isSQLWordChar(s[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenWord, text: s[start:i]})
+		case c == '=' || c == '<' || c == '>' || c == '!':
+			for i < len(s) && (s[i] == '=' || s[i] == '<' || s[i] == '>' || s[i] == '!') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenOperator, text: s[start:i]})
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			tokens = append(tokens, sqlToken{kind: sqlTokenOther, text: s[start:i]})
+		}
+	}
+	return tokens
+}
+
+// skipQuoted 返回从 s[start] 开始的被 quote 包围的文本之后的位置, 两个连续的 quote 表示 quote 本身
+func skipQuoted(s string, start int, quote byte) int {
+	i := start + 1
+	for i < len(s) {
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote && quote != ']' {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(s)
+}
+
+// columnName 返回标识符记号中的列名, 去掉表名和引号
+func columnName(tok sqlToken) string {
+	switch tok.kind {
+	case sqlTokenWord:
+		if idx := strings.LastIndexByte(tok.text, '.'); idx >= 0 {
+			return tok.text[idx+1:]
+		}
+		return tok.text
+	case sqlTokenIdent:
+		if len(tok.text) >= 2 {
+			return tok.text[1 : len(tok.text)-1]
+		}
+	}
+	return ""
+}
+
+// assignSQLColumns 为占位符推断它对应的列名
+func assignSQLColumns(tokens []sqlToken) {
+	prev := func(i int) int {
+		for i--; i >= 0 && tokens[i].kind == sqlTokenSpace; i-- {
+		}
+		return i
+	}
+
+	// col = ?, col LIKE ? 等
+	for i := range tokens {
+		if tokens[i].kind != sqlTokenPlaceholder {
+			continue
+		}
+		op := prev(i)
+		if op < 0 {
+			continue
+		}
+		isOp := tokens[op].kind == sqlTokenOperator
+		if tokens[op].kind == sqlTokenWord {
+			switch strings.ToUpper(tokens[op].text) {
+			case "LIKE", "ILIKE":
+				isOp = true
+			}
+		}
+		if !isOp {
+			continue
+		}
+		if col := prev(op); col >= 0 {
+			tokens[i].column = columnName(tokens[col])
+		}
+	}
+
+	// INSERT INTO t (a, b) VALUES (?, ?), (?, ?)
+	var columns []string
+	state, depth, pos := 0, 0, 0
+	for i := range tokens {
+		tok := tokens[i]
+		if tok.kind == sqlTokenSpace {
+			continue
+		}
+		upper := ""
+		if tok.kind == sqlTokenWord {
+			upper = strings.ToUpper(tok.text)
+		}
+		switch state {
+		case 0: // 等待 INSERT
+			if upper == "INSERT" || upper == "REPLACE" {
+				state, columns = 1, nil
+			}
+		case 1: // 等待列名列表
+			if tok.text == "(" {
+				state = 2
+			} else if upper == "VALUES" || upper == "SELECT" {
+				state = 0
+			}
+		case 2: // 列名列表
+			switch {
+			case tok.text == ")":
+				state = 3
+			case tok.kind == sqlTokenWord || tok.kind == sqlTokenIdent:
+				columns = append(columns, columnName(tok))
+			}
+		case 3: // 等待 VALUES
+			if upper == "VALUES" {
+				state, depth = 4, 0
+			} else {
+				state = 0
+			}
+		case 4: // VALUES 中的元组
+			switch {
+			case tok.text == "(":
+				depth++
+				if depth == 1 {
+					pos = 0
+				}
+			case tok.text == ")":
+				depth--
+			case tok.text == "," && depth == 1:
+				pos++
+			case tok.kind == sqlTokenPlaceholder && depth == 1:
+				if pos < len(columns) && tokens[i].column == "" {
+					tokens[i].column = columns[pos]
+				}
+			case depth == 0 && tok.text != ",":
+				state = 0
+			}
+		}
+	}
+}
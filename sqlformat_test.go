@@ -0,0 +1,168 @@
+package errors
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func placeholders(tokens []sqlToken) []sqlToken {
+	var list []sqlToken
+	for _, tok := range tokens {
+		if tok.kind == sqlTokenPlaceholder {
+			list = append(list, tok)
+		}
+	}
+	return list
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect SQLDialect
+		want    []string // 占位符的文本
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", DialectAuto, []string{"?", "?"}},
+		{"SELECT * FROM t WHERE a = $1 AND b = $12", DialectAuto, []string{"$1", "$12"}},
+		{"SELECT * FROM t WHERE a = :a AND b = @b", DialectAuto, []string{":a", "@b"}},
+		{"SELECT * FROM t WHERE a = :1", DialectNamed, []string{":1"}},
+		{"SELECT '?', 'it''s $1', \"?\", `:a` FROM t WHERE a = ?", DialectAuto, []string{"?"}},
+		{"SELECT 1 -- ? $1\nFROM t /* :a ? */ WHERE a = ?", DialectAuto, []string{"?"}},
+		{"SELECT a::text, b::int FROM t WHERE c = $1::uuid", DialectAuto, []string{"$1"}},
+		{"SELECT data ? 'key' FROM t WHERE id = $1", DialectDollar, []string{"$1"}},
+		{"SELECT @@version, a FROM t WHERE a = ?", DialectAuto, []string{"?"}},
+		{"SELECT [a?] FROM t WHERE a = @a", DialectNamed, []string{"@a"}},
+		{"SELECT * FROM t WHERE a = ? AND b = :b", DialectQuestion, []string{"?"}},
+	}
+	for _, test := range tests {
+		tokens := tokenizeSQL(test.sql, test.dialect)
+
+		var sb strings.Builder
+		for _, tok := range tokens {
+			sb.WriteString(tok.text)
+		}
+		if sb.String() != test.sql {
+			t.Errorf("%q: tokens do not cover the statement: %q", test.sql, sb.String())
+		}
+
+		var got []string
+		for _, tok := range placeholders(tokens) {
+			got = append(got, tok.text)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestAssignSQLColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT * FROM users u WHERE u.name = ? AND \"Password\" <> ? AND email LIKE ?", []string{"name", "Password", "email"}},
+		{"UPDATE users SET password = $1, age = $2 WHERE id = $3", []string{"password", "age", "id"}},
+		{"INSERT INTO users (name, `token`, age) VALUES (?, ?, ?), (?, ?, ?)", []string{"name", "token", "age", "name", "token", "age"}},
+		{"INSERT INTO users (name, age) VALUES (?, now()), (lower(?), ?)", []string{"name", "", "age"}},
+		{"INSERT INTO users SELECT * FROM t WHERE a = ?", []string{"a"}},
+		{"UPDATE users SET password = crypt($1, password) WHERE id = $2", []string{"", "id"}},
+	}
+	for _, test := range tests {
+		tokens := tokenizeSQL(test.sql, DialectAuto)
+		assignSQLColumns(tokens)
+
+		var got []string
+		for _, tok := range placeholders(tokens) {
+			got = append(got, tok.column)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestSQLFormatterRender(t *testing.T) {
+	f := &SQLFormatter{
+		MaxValueLen:   5,
+		RedactArgs:    []int{3},
+		RedactColumns: []string{"password"},
+	}
+	tests := []struct {
+		sql      string
+		args     []interface{}
+		want     string
+		used     int
+		redacted []bool
+	}{
+		{
+			"SELECT * FROM t WHERE a = ? AND b = ? AND c = ?",
+			[]interface{}{1, "it's", "x"},
+			"SELECT * FROM t WHERE a = 1 AND b = 'it''s' AND c = '***'",
+			3, []bool{false, false, true},
+		},
+		{
+			"UPDATE users SET password = $2 WHERE id = $1 AND name = $1",
+			[]interface{}{7, "secret"},
+			"UPDATE users SET password = '***' WHERE id = 7 AND name = 7",
+			3, []bool{false, true},
+		},
+		{
+			"INSERT INTO users (name, password) VALUES (?, ?)",
+			[]interface{}{"abcdefgh", "secret"},
+			"INSERT INTO users (name, password) VALUES ('abcde...', '***')",
+			2, []bool{false, true},
+		},
+		{
+			"SELECT * FROM t WHERE a = :a AND b = :b AND c = :a",
+			[]interface{}{sql.Named("b", true), sql.Named("a", nil)},
+			"SELECT * FROM t WHERE a = NULL AND b = TRUE AND c = NULL",
+			3, []bool{false, false},
+		},
+		{
+			"SELECT * FROM t WHERE a = ? AND b = ?",
+			[]interface{}{[]byte{0xab}},
+			"SELECT * FROM t WHERE a = X'AB' AND b = ?",
+			1, []bool{false},
+		},
+		{
+			"SELECT a::text FROM t WHERE a = $1::int",
+			[]interface{}{2},
+			"SELECT a::text FROM t WHERE a = 2::int",
+			1, []bool{false},
+		},
+		{
+			"SELECT '?' FROM t -- ?",
+			[]interface{}{2},
+			"SELECT '?' FROM t -- ?",
+			0, []bool{false},
+		},
+	}
+	for _, test := range tests {
+		got, used, redacted := f.render(test.sql, test.args)
+		if got != test.want {
+			t.Errorf("%q:\n got %q\nwant %q", test.sql, got, test.want)
+		}
+		if used != test.used {
+			t.Errorf("%q: used got %d, want %d", test.sql, used, test.used)
+		}
+		if !reflect.DeepEqual(redacted, test.redacted) {
+			t.Errorf("%q: redacted got %v, want %v", test.sql, redacted, test.redacted)
+		}
+	}
+}
+
+func TestWithSQLGoSyntaxRedacted(t *testing.T) {
+	err := WrapSQLError(ErrTimeout, "UPDATE users SET password = $1 WHERE id = $2", []interface{}{"secret", 7})
+	s := fmt.Sprintf("%#v", err)
+	if strings.Contains(s, "secret") {
+		t.Errorf("%%#v leaks the password: %s", s)
+	}
+	if !strings.Contains(s, `"7"`) {
+		t.Errorf("%%#v lost the other args: %s", s)
+	}
+	if s := fmt.Sprintf("%+v", err); strings.Contains(s, "secret") {
+		t.Errorf("%%+v leaks the password: %s", s)
+	}
+}
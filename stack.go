@@ -0,0 +1,144 @@
+package errors
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	emperrors "emperror.dev/errors"
+)
+
+// StackPolicy 决定创建或包装 *Error 时是否记录调用栈
+type StackPolicy int32
+
+const (
+	// StackNever 不记录调用栈, 这是默认值
+	StackNever StackPolicy = iota
+	// StackServerErrors 只为 5xx 的错误记录调用栈
+	StackServerErrors
+	// StackAlways 总是记录调用栈
+	StackAlways
+)
+
+const maxStackDepth = 32
+
+var stackPolicy int32 = int32(StackNever)
+
+// SetStackPolicy 设置记录调用栈的策略
+func SetStackPolicy(policy StackPolicy) {
+	atomic.StoreInt32(&stackPolicy, int32(policy))
+}
+
+// GetStackPolicy 返回当前记录调用栈的策略
+func GetStackPolicy() StackPolicy {
+	return StackPolicy(atomic.LoadInt32(&stackPolicy))
+}
+
+// StackTracer 是带有调用栈的错误, 与 emperror 和 github.com/pkg/errors 使用的接口相同,
+// 所以 emperror 中识别调用栈的 handler 可以直接使用它
+type StackTracer interface {
+	StackTrace() emperrors.StackTrace
+}
+
+var _ StackTracer = &Error{}
+var _ StackTracer = errwrap{}
+var _ StackTracer = &withStack{}
+
+// captureStack 按当前策略为 code 记录调用栈, skip 为要跳过的 captureStack 的调用者层数
+func captureStack(code int, skip int) []uintptr {
+	switch GetStackPolicy() {
+	case StackAlways:
+	case StackServerErrors:
+		if ToHttpCode(code) < 500 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return callers(skip + 1)
+}
+
+// stackRef 返回指向 pcs 的指针, 没有调用栈时返回 nil
+func stackRef(pcs []uintptr) *[]uintptr {
+	if len(pcs) == 0 {
+		return nil
+	}
+	return &pcs
+}
+
+// frames 返回包装错误时记录的调用栈
+func (e errwrap) frames() []uintptr {
+	if e.stack == nil {
+		return nil
+	}
+	return *e.stack
+}
+
+func callers(skip int) []uintptr {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	return append([]uintptr(nil), pcs[:n]...)
+}
+
+func toStackTrace(pcs []uintptr) emperrors.StackTrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+	st := make(emperrors.StackTrace, len(pcs))
+	for idx, pc := range pcs {
+		st[idx] = emperrors.Frame(pc)
+	}
+	return st
+}
+
+// StackTrace 返回创建错误时记录的调用栈, 没有记录时返回 Cause 链中第一个调用栈
+func (err *Error) StackTrace() emperrors.StackTrace {
+	if len(err.stack) > 0 {
+		return toStackTrace(err.stack)
+	}
+	return causeStackTrace(err.Cause)
+}
+
+// StackTrace 返回包装错误时记录的调用栈, 没有记录时返回被包装的错误的调用栈
+func (e errwrap) StackTrace() emperrors.StackTrace {
+	if pcs := e.frames(); len(pcs) > 0 {
+		return toStackTrace(pcs)
+	}
+	return causeStackTrace(e.err)
+}
+
+// StackTrace 返回发生 panic 时的调用栈
+func (w *withStack) StackTrace() emperrors.StackTrace {
+	return toStackTrace(w.pcs)
+}
+
+func causeStackTrace(err error) emperrors.StackTrace {
+	var st StackTracer
+	if err != nil && As(err, &st) {
+		return st.StackTrace()
+	}
+	return nil
+}
+
+// StackFrames 返回 err 链中第一个调用栈的各个栈帧
+func StackFrames(err error) []runtime.Frame {
+	st := causeStackTrace(err)
+	if len(st) == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, len(st))
+	for idx, f := range st {
+		pcs[idx] = uintptr(f)
+	}
+
+	var list []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		list = append(list, frame)
+		if !more {
+			break
+		}
+	}
+	return list
+}
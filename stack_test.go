@@ -0,0 +1,119 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func withStackPolicy(t *testing.T, policy StackPolicy) {
+	old := GetStackPolicy()
+	SetStackPolicy(policy)
+	t.Cleanup(func() { SetStackPolicy(old) })
+}
+
+func TestErrwrapComparable(t *testing.T) {
+	for _, policy := range []StackPolicy{StackNever, StackAlways} {
+		withStackPolicy(t, policy)
+
+		for _, a := range []error{Wrap(io.EOF, "x"), WrapWithMessage(io.EOF, "x"), WrapWithSuffix(io.EOF, "x")} {
+			if _, ok := a.(errwrap); !ok {
+				t.Fatalf("got %T, want errwrap", a)
+			}
+			b := a
+			if a != b {
+				t.Errorf("policy %d: a copy should be equal", policy)
+			}
+			m := map[error]int{a: 1}
+			if m[b] != 1 {
+				t.Errorf("policy %d: map lookup failed", policy)
+			}
+			if a == io.EOF {
+				t.Errorf("policy %d: should not equal the wrapped error", policy)
+			}
+		}
+	}
+}
+
+func panicAndRecover() (err error) {
+	defer HandlePanic(&err, "worker")
+	panic("boom")
+}
+
+func TestHandlePanicMessage(t *testing.T) {
+	err := panicAndRecover()
+	if err.Error() != "worker: boom" {
+		t.Errorf("Error: got %q", err.Error())
+	}
+	st, ok := err.(StackTracer)
+	if !ok || len(st.StackTrace()) == 0 {
+		t.Fatal("the panic stack should be kept as frames")
+	}
+	if s := fmt.Sprintf("%+v", err); !strings.Contains(s, "stack:") || !strings.Contains(s, "panicAndRecover") {
+		t.Errorf("%%+v should print the stack: %s", s)
+	}
+
+	c := NewCollector("failed")
+	c.Go(func() error { panic("boom") })
+	c.Go(func() error { return io.EOF })
+	e := ToError(c.Wait())
+	for _, internal := range e.Internals {
+		if strings.Contains(internal.Message, "goroutine") || strings.Contains(internal.Message, "\n") {
+			t.Errorf("the stack leaks into the message: %q", internal.Message)
+		}
+	}
+}
+
+func TestStackPolicy(t *testing.T) {
+	tests := []struct {
+		policy      StackPolicy
+		clientStack bool
+		serverStack bool
+	}{
+		{StackNever, false, false},
+		{StackServerErrors, false, true},
+		{StackAlways, true, true},
+	}
+	for _, test := range tests {
+		withStackPolicy(t, test.policy)
+
+		cases := []struct {
+			err   error
+			stack bool
+		}{
+			{NewError(ErrNotFound.Code, "a"), test.clientStack},
+			{NewError(http.StatusInternalServerError, "b"), test.serverStack},
+			{Wrap(ErrNotFound, "c"), test.clientStack},
+			{Wrap(ErrTimeout, "d"), test.serverStack},
+			{WrapWithMessage(ErrNotFound, "e"), test.clientStack},
+			{Wrap(io.EOF, "f"), test.serverStack},
+			{Wrap(NewError(http.StatusBadRequest, "g"), "h"), test.clientStack},
+		}
+		for idx, c := range cases {
+			frames := StackFrames(c.err)
+			if (len(frames) > 0) != c.stack {
+				t.Errorf("policy %d, case %d: stack got %d frames", test.policy, idx, len(frames))
+				continue
+			}
+			if c.stack && !strings.HasSuffix(frames[0].Function, "TestStackPolicy") {
+				t.Errorf("policy %d, case %d: the first frame should be the caller, got %s",
+					test.policy, idx, frames[0].Function)
+			}
+		}
+	}
+}
+
+func TestStackNotCopiedByWrap(t *testing.T) {
+	withStackPolicy(t, StackAlways)
+
+	inner := NewError(http.StatusInternalServerError, "inner")
+	outer := Wrap(inner, "outer")
+	if fmt.Sprint(inner.StackTrace()) != fmt.Sprint(outer.(*Error).StackTrace()) {
+		t.Error("Wrap should keep the stack of the wrapped *Error")
+	}
+	if n := strings.Count(fmt.Sprintf("%+v", outer), "stack:"); n != 1 {
+		t.Errorf("%%+v should print the stack once, got %d", n)
+	}
+}
@@ -15,6 +15,47 @@ type Wrapper interface {
 	Unwrap() error
 }
 
+// maxChainDepth 遍历错误链的最大深度, 防止错误链中有环时死循环
+const maxChainDepth = 100
+
+// Walk 深度优先地遍历 err 的错误链, 依次访问 err 本身和它包装的错误, 直到 fn 返回 true,
+// 返回值表示是否有 fn 返回了 true。
+//
+// 包装的错误按下面的顺序查找:
+//
+//	Unwrap() []error  按顺序访问每一个成员
+//	Unwrap() error
+//	Cause() error
+func Walk(err error, fn func(error) bool) bool {
+	return walk(err, fn, 0)
+}
+
+func walk(err error, fn func(error) bool, depth int) bool {
+	for err != nil && depth < maxChainDepth {
+		if fn(err) {
+			return true
+		}
+		depth++
+
+		switch x := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range x.Unwrap() {
+				if walk(e, fn, depth) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Cause() error }:
+			err = x.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
 // Opaque returns an error with the same error formatting as err
 // but that does not match err and cannot be unwrapped.
 func Opaque(err error) error {
@@ -0,0 +1,111 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// causeOnly 只实现了 Cause() error, 如 github.com/pkg/errors 的错误
+type causeOnly struct{ err error }
+
+func (e causeOnly) Error() string { return "cause only: " + e.err.Error() }
+func (e causeOnly) Cause() error  { return e.err }
+
+// httpOnly 只实现了 HTTPCoder
+type httpOnly struct{ code int }
+
+func (e httpOnly) Error() string { return http.StatusText(e.code) }
+func (e httpOnly) HTTPCode() int { return e.code }
+
+func TestWalkOrder(t *testing.T) {
+	a, b, c := stderrors.New("a"), stderrors.New("b"), stderrors.New("c")
+	err := Wrap(stderrors.Join(fmt.Errorf("x: %w", a), causeOnly{b}), "outer")
+	err = fmt.Errorf("%w, %w", err, c)
+
+	var got []error
+	Walk(err, func(e error) bool {
+		if e == a || e == b || e == c {
+			got = append(got, e)
+		}
+		return false
+	})
+	want := []error{a, b, c}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("%d: got %v, want %v", idx, got[idx], want[idx])
+		}
+	}
+
+	if !Walk(err, func(e error) bool { return e == a }) {
+		t.Error("Walk should report the match")
+	}
+	if Walk(nil, func(error) bool { return true }) {
+		t.Error("Walk(nil) should not call fn")
+	}
+}
+
+func TestChainCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     int
+		httpCode int
+		notFound bool
+		typeErr  bool
+	}{
+		{"errwrap", Wrap(ErrRecordNotFound, "load"), ErrRecordNotFound.Code, 404, true, false},
+		{"WithTitle", WithTitle(ErrTypeError, "convert"), ErrTypeError.Code, 460, false, true},
+		{"WithSQL", WrapSQLError(ErrRecordNotFound, "SELECT 1", nil), ErrRecordNotFound.Code, 404, true, false},
+		{"withStack", &withStack{err: ErrTypeError}, ErrTypeError.Code, 460, false, true},
+		{"fmt.Errorf", fmt.Errorf("load: %w", ErrNotFound), ErrNotFound.Code, 404, true, false},
+		{"errors.Join", stderrors.Join(io.ErrUnexpectedEOF, ErrTypeError), ErrTypeError.Code, 460, false, true},
+		{"Cause", causeOnly{ErrRecordNotFound}, ErrRecordNotFound.Code, 404, true, false},
+		{"nested", fmt.Errorf("x: %w", causeOnly{Wrap(WrapSQLError(ErrTypeError, "SELECT 1", nil), "y")}), ErrTypeError.Code, 460, false, true},
+		{"outer wins", fmt.Errorf("x: %w", &Error{Code: ErrConflict.Code, Message: "conflict", Cause: ErrNotFound}), ErrConflict.Code, 409, false, false},
+		{"first member wins", stderrors.Join(Wrap(ErrTypeError, "a"), ErrNotFound), ErrTypeError.Code, 460, false, true},
+		{"HTTPCoder", fmt.Errorf("call: %w", httpOnly{http.StatusNotFound}), 0, 404, true, false},
+		{"Wrap HTTPCoder", Wrap(httpOnly{http.StatusNotFound}, "call"), 404, 404, true, false},
+		{"Wrap WithSQL", Wrap(WrapSQLError(ErrRecordNotFound, "SELECT 1", nil), "query"), ErrRecordNotFound.Code, 404, true, false},
+		{"WrapWithSuffix Join", WrapWithSuffix(stderrors.Join(io.EOF, ErrTypeError), "parse"), ErrTypeError.Code, 460, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, ok := GetErrorCode(test.err)
+			if test.code == 0 {
+				if ok {
+					t.Errorf("GetErrorCode: got %d, want none", code)
+				}
+			} else if !ok || code != test.code {
+				t.Errorf("GetErrorCode: got %d %v, want %d", code, ok, test.code)
+			}
+			if hc, ok := GetHttpCode(test.err); !ok || hc != test.httpCode {
+				t.Errorf("GetHttpCode: got %d %v, want %d", hc, ok, test.httpCode)
+			}
+			if got := IsNotFound(test.err); got != test.notFound {
+				t.Errorf("IsNotFound: got %v", got)
+			}
+			if got := IsTypeError(test.err); got != test.typeErr {
+				t.Errorf("IsTypeError: got %v", got)
+			}
+		})
+	}
+}
+
+func TestChainCodesUnknown(t *testing.T) {
+	err := fmt.Errorf("x: %w", stderrors.New("plain"))
+	if code, ok := GetErrorCode(err); ok {
+		t.Errorf("GetErrorCode: got %d", code)
+	}
+	if hc, ok := GetHttpCode(err); ok {
+		t.Errorf("GetHttpCode: got %d", hc)
+	}
+	if IsNotFound(err) || IsTypeError(err) {
+		t.Error("a plain error is neither not found nor a type error")
+	}
+}
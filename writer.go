@@ -0,0 +1,151 @@
+package errors
+
+import (
+	"encoding/json"
+	"html"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrorCodeHeader WriteError 写入错误码的响应头, ToResponseError 会读取它
+const ErrorCodeHeader = "X-Error-Code"
+
+var errorContentTypes = []string{
+	"application/json",
+	ProblemContentType,
+	"text/plain",
+	"text/html",
+}
+
+// WriteError 将 err 写到 HTTP 响应中
+//
+// 状态码由 HTTPCode(err) 决定, 响应格式按请求的 Accept 头在 JSON, problem+json,
+// 纯文本和 HTML 之间协商, 默认为 JSON。204, 304 和 1xx 这类不能带 body 的状态码,
+// 以及 HEAD 请求只写响应头。错误链中有重试等待时间时写 Retry-After 头。
+// err 为 nil 时写一个 500 错误。
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if isNilError(err) {
+		err = NewInternalError(http.StatusText(http.StatusInternalServerError))
+	}
+	e := ToError(err)
+	status := HTTPCode(err)
+	if status < 100 || status > 999 {
+		status = http.StatusInternalServerError
+	}
+
+	header := w.Header()
+	header.Set(ErrorCodeHeader, strconv.Itoa(e.Code))
+	if after := RetryAfter(err); after > 0 {
+		header.Set("Retry-After", formatRetryAfter(after))
+	}
+	if !bodyAllowedForStatus(status) || (r != nil && r.Method == http.MethodHead) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(status)
+		return
+	}
+
+	var accept string
+	if r != nil {
+		accept = r.Header.Get("Accept")
+	}
+
+	var body []byte
+	var marshalErr error
+	contentType := negotiateErrorContentType(accept)
+	switch contentType {
+	case ProblemContentType:
+		var instance string
+		if r != nil && r.URL != nil {
+			instance = r.URL.Path
+		}
+		problem := ToProblem(e, instance)
+		problem.Status = status
+		body, marshalErr = json.Marshal(problem)
+	case "text/plain":
+		body = []byte(e.Error())
+	case "text/html":
+		body = []byte("<!DOCTYPE html>\n<html><head><title>" + html.EscapeString(http.StatusText(status)) +
+			"</title></head><body><h1>" + html.EscapeString(e.Message) + "</h1><pre>" +
+			html.EscapeString(e.Error()) + "</pre></body></html>\n")
+	default:
+		body, marshalErr = json.Marshal(e)
+	}
+	if marshalErr != nil {
+		contentType = "text/plain"
+		body = []byte(e.Error())
+	}
+
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiateErrorContentType 按 Accept 头选择一个错误响应的格式
+func negotiateErrorContentType(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return errorContentTypes[0]
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				q = f
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, ar := range ranges {
+		for _, contentType := range errorContentTypes {
+			if matchMediaRange(ar.mediaType, contentType) {
+				return contentType
+			}
+		}
+	}
+	return errorContentTypes[0]
+}
+
+func matchMediaRange(mediaRange, contentType string) bool {
+	if mediaRange == "*/*" || mediaRange == contentType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(contentType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteErrorNil(t *testing.T) {
+	var nilErr *Error
+	for _, err := range []error{nil, nilErr} {
+		w := httptest.NewRecorder()
+		WriteError(w, httptest.NewRequest(http.MethodGet, "/", nil), err)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("status: got %d", w.Code)
+		}
+		if w.Header().Get(ErrorCodeHeader) != "500" {
+			t.Errorf("%s: got %q", ErrorCodeHeader, w.Header().Get(ErrorCodeHeader))
+		}
+	}
+}
+
+func TestWriteErrorNegotiation(t *testing.T) {
+	err := NewError(ErrRecordNotFound.Code, "user <1> not found")
+	code := `"code":` + strconv.Itoa(ErrRecordNotFound.Code)
+	tests := []struct {
+		accept      string
+		contentType string
+		contains    string
+	}{
+		{"", "application/json", code},
+		{"application/json", "application/json", code},
+		{"application/problem+json", ProblemContentType, `"instance":"/users/1"`},
+		{"text/plain", "text/plain", "user <1> not found"},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html", "<h1>user &lt;1&gt; not found</h1>"},
+		{"text/plain;q=0.5, application/json", "application/json", code},
+		{"image/png, text/*;q=0.1", "text/plain", "user <1> not found"},
+		{"image/png", "application/json", code},
+		{"text/plain;q=0, */*", "application/json", code},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		WriteError(w, r, err)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%q: status got %d", test.accept, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.contentType+"; charset=utf-8" {
+			t.Errorf("%q: content type got %q", test.accept, ct)
+		}
+		if !strings.Contains(w.Body.String(), test.contains) {
+			t.Errorf("%q: body got %s", test.accept, w.Body.String())
+		}
+		if w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+			t.Errorf("%q: content length got %q", test.accept, w.Header().Get("Content-Length"))
+		}
+	}
+}
+
+func TestWriteErrorWithoutBody(t *testing.T) {
+	tests := []struct {
+		method string
+		err    error
+		status int
+	}{
+		{http.MethodGet, ErrNoContent, http.StatusNoContent},
+		{http.MethodGet, NewError(http.StatusNotModified, "not modified"), http.StatusNotModified},
+		{http.MethodHead, ErrNotFound, http.StatusNotFound},
+		{http.MethodHead, NewInternalError("boom"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Type", "application/json")
+		WriteError(w, httptest.NewRequest(test.method, "/", nil), test.err)
+
+		if w.Code != test.status {
+			t.Errorf("%s %v: status got %d", test.method, test.err, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("%s %v: body got %q", test.method, test.err, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "" {
+			t.Errorf("%s %v: content type got %q", test.method, test.err, ct)
+		}
+		if w.Header().Get(ErrorCodeHeader) != strconv.Itoa(ToError(test.err).Code) {
+			t.Errorf("%s %v: %s got %q", test.method, test.err, ErrorCodeHeader, w.Header().Get(ErrorCodeHeader))
+		}
+	}
+}
+
+func TestWriteErrorCodeRoundTrip(t *testing.T) {
+	for _, accept := range []string{"application/json", ProblemContentType, "text/plain", "text/html"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		WriteError(w, r, Wrap(ErrRecordNotFound, "load user"))
+
+		err := ToResponseError(w.Result())
+		if !stderrors.Is(err, ErrRecordNotFound) {
+			t.Errorf("%s: %v is not ErrRecordNotFound", accept, err)
+		}
+		if code, _ := GetErrorCode(err); code != ErrRecordNotFound.Code {
+			t.Errorf("%s: code got %d", accept, code)
+		}
+	}
+
+	// 没有 body 时也保留错误码
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodHead, "/", nil), ErrRecordNotFound)
+	if code, _ := GetErrorCode(ToResponseError(w.Result())); code != ErrRecordNotFound.Code {
+		t.Errorf("HEAD: code got %d", code)
+	}
+
+	// 与状态码不一致的错误码被忽略
+	w = httptest.NewRecorder()
+	w.Header().Set(ErrorCodeHeader, "404001")
+	w.WriteHeader(http.StatusBadGateway)
+	if code, _ := GetErrorCode(ToResponseError(w.Result())); code != http.StatusBadGateway {
+		t.Errorf("mismatch: code got %d", code)
+	}
+}